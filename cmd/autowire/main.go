@@ -1,29 +1,221 @@
 package main
 
 import (
+	"errors"
 	"flag"
+	"fmt"
 	"log/slog"
 	"os"
+	"slices"
+	"strings"
 
+	"github.com/hauntedness/autowire/conf"
 	"github.com/hauntedness/autowire/pkg"
 )
 
+var (
+	verbose  = flag.Bool("v", false, "verbose output")
+	tags     = flag.String("tags", "", "additional build tags, comma separated, wireinject is always set")
+	dir      = flag.String("dir", "", "directory in which to run, default to current directory")
+	dryRun   = flag.Bool("dry-run", false, "complete the injectors but do not rewrite source files")
+	diff     = flag.Bool("diff", false, "print the changes as unified diff instead of rewriting source files")
+	check    = flag.Bool("check", false, "report injectors missing providers and exit non-zero, without rewriting source files")
+	structs  = flag.Bool("struct", false, `provide struct types without provider function by wire.Struct(new(T), "*")`)
+	strict   = flag.Bool("strict", false, "fail instead of choosing one when a bean has multiple providers")
+	explain  = flag.Bool("explain", false, "print how the provider of each bean is chosen to stderr")
+	gen      = flag.Bool("gen", false, "write wire_gen.go with the generator of wire after completing the injectors")
+	backend  = flag.String("backend", "wire", "generator used by -gen, wire writes wire_gen.go, go writes plain go code in x_autowire_gen.go")
+	prune    = flag.Bool("prune", false, "remove provider functions in wire.Build not needed by the injector output")
+	pruneAll = flag.Bool("prune-all", false, "like -prune, but also remove unused provider sets, binds, structs and values")
+	sortArgs = flag.Bool("sort", false, "sort all arguments of wire.Build in dependency order, not only the added ones")
+	config   = flag.String("config", "", "path of the config file, default to autowire.json found by walking up from the working directory to the module root")
+)
+
 func main() {
-	initLogger()
-	di := pkg.NewDIContext(nil)
-	di.Process("pattern=.")
+	flag.Usage = usage
+	flag.Parse()
+	initLogger(*verbose)
+	patterns := flag.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	cfg, err := readConfig()
+	if err != nil {
+		exit(err)
+	}
+	if *check && (*gen || *diff) {
+		fmt.Fprintln(os.Stderr, "-check can not be used with -gen or -diff")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *backend != "wire" && *backend != "go" {
+		exit(fmt.Errorf("unknown backend %q, should be one of wire, go", *backend))
+	}
+	procConf := pkg.NewDefaultProcessConfigurer(cfg)
+	procConf.RewriteSource = procConf.RewriteSource && !*dryRun && !*diff
+	procConf.StructProvider = *structs
+	procConf.Strict = *strict
+	procConf.Sort = *sortArgs
+	switch {
+	case *pruneAll:
+		procConf.Prune = pkg.PruneAll
+	case *prune:
+		procConf.Prune = pkg.PruneFuncs
+	}
+	buildTags := conf.SplitTags(append(cfg.Tags, *tags)...)
+	loadConf := conf.New(*dir, strings.Join(buildTags, ","))
+	switch patterns[0] {
+	case "graph":
+		procConf.RewriteSource = false
+		graph(pkg.NewDIContext(procConf, loadConf), patterns[1:])
+		return
+	case "explain":
+		procConf.RewriteSource = false
+		explainBean(pkg.NewDIContext(procConf, loadConf), patterns[1:])
+		return
+	}
+	di := pkg.NewDIContext(procConf, loadConf)
+	if *check {
+		err = di.Check(patterns...)
+	} else {
+		err = di.Process(patterns...)
+	}
+	if *explain {
+		if werr := pkg.WriteElections(os.Stderr, di.Elections("")); werr != nil {
+			exit(werr)
+		}
+	}
+	if *check {
+		if err != nil {
+			exit(err)
+		}
+		return
+	}
+	// the injectors resolved are generated and printed even if others fail, the errors are reported at last
+	if *gen {
+		// errors of Process are kept, as they are in the ErrorList of the context
+		if *backend == "go" {
+			err = di.GenerateGo()
+		} else {
+			err = di.Generate(strings.Join(buildTags, " "))
+		}
+	}
+	if *diff {
+		if derr := di.Diff(os.Stdout); derr != nil {
+			exit(derr)
+		}
+	}
+	if err != nil {
+		exit(err)
+	}
+}
+
+// readConfig read the file given by -config, or autowire.json found by walking up from the working directory to the module root,
+// an empty config is returned if there is none
+func readConfig() (*conf.Config, error) {
+	path := *config
+	if path == "" {
+		start := *dir
+		if start == "" {
+			start = "."
+		}
+		found, err := conf.Find(start)
+		if err != nil {
+			return nil, err
+		}
+		if found == "" {
+			return &conf.Config{}, nil
+		}
+		path = found
+	}
+	slog.Debug("read config", "path", path)
+	return conf.Read(path)
+}
+
+// graph print the dependency graphs of the injectors, args are the flags and packages after the graph command
+func graph(di *pkg.DIContext, args []string) {
+	fs := flag.NewFlagSet("graph", flag.ExitOnError)
+	format := fs.String("format", "dot", "output format, one of dot, mermaid, json")
+	injector := fs.String("injector", "", "only print the injector with this name, e.g. InitApp or github.com/x/y.InitApp")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: autowire [flags] graph [-format dot|mermaid|json] [-injector name] [packages]\n\n")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	patterns := fs.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	graphs, err := di.Graph(patterns...)
+	if *injector != "" {
+		graphs = slices.DeleteFunc(graphs, func(g *pkg.Graph) bool {
+			return g.Name() != *injector && g.Injector != *injector
+		})
+		if len(graphs) == 0 && err == nil {
+			exit(fmt.Errorf("injector %s not found", *injector))
+		}
+	}
+	if werr := pkg.WriteGraph(os.Stdout, *format, graphs); werr != nil {
+		exit(werr)
+	}
+	if err != nil {
+		exit(err)
+	}
+}
+
+// explainBean print how the provider of the bean is chosen in each injector, args are the bean type and packages
+func explainBean(di *pkg.DIContext, args []string) {
+	if len(args) == 0 {
+		exit(errors.New("usage: autowire [flags] explain <bean-type> [packages]"))
+	}
+	typ, patterns := args[0], args[1:]
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	err := di.Resolve(patterns...)
+	elections := di.Elections(typ)
+	if len(elections) == 0 && err == nil {
+		exit(fmt.Errorf("no injector needs %s", typ))
+	}
+	if werr := pkg.WriteElections(os.Stdout, elections); werr != nil {
+		exit(werr)
+	}
+	if err != nil {
+		exit(err)
+	}
+}
+
+// exit print err as file:line:col: message and exit with non-zero code
+func exit(err error) {
+	var list pkg.ErrorList
+	if errors.As(err, &list) {
+		for _, e := range list {
+			fmt.Fprintln(os.Stderr, e.Error())
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, err.Error())
+	}
+	os.Exit(1)
+}
+
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), "usage: autowire [flags] [packages]\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "       autowire [flags] graph [-format dot|mermaid|json] [-injector name] [packages]\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "       autowire [flags] explain <bean-type> [packages]\n\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "packages default to \".\", patterns like ./... are supported\n\n")
+	flag.PrintDefaults()
 }
 
-func initLogger() {
-	verbose := flag.Bool("v", false, "verbose output")
+func initLogger(verbose bool) {
 	level := slog.LevelWarn
-	if *verbose {
+	if verbose {
 		level = slog.LevelDebug
 	}
 	opts := slog.HandlerOptions{
 		AddSource: true,
 		Level:     level,
 	}
-	handler := slog.NewTextHandler(os.Stdout, &opts)
+	// stdout is left for the output of -diff and graph
+	handler := slog.NewTextHandler(os.Stderr, &opts)
 	slog.SetDefault(slog.New(handler))
 }
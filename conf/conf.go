@@ -1,18 +1,42 @@
 package conf
 
 import (
+	"slices"
+	"strings"
+
 	"golang.org/x/tools/go/packages"
 )
 
-var DefaultConf = func() *packages.Config {
+var DefaultConf = New("", "")
+
+// New create the config used to load packages,
+// dir is the working directory, tags is a comma or space separated list of extra build tags
+func New(dir string, tags string) *packages.Config {
+	buildTags := append([]string{"wireinject"}, SplitTags(tags)...)
 	return &packages.Config{
-		BuildFlags: []string{"-tags=wireinject"},
+		Dir:        dir,
+		BuildFlags: []string{"-tags=" + strings.Join(buildTags, ",")},
 		Mode: packages.NeedName |
 			packages.NeedFiles |
 			packages.NeedDeps |
 			packages.NeedImports |
 			packages.NeedTypes |
 			packages.NeedTypesInfo |
-			packages.NeedSyntax,
+			packages.NeedSyntax |
+			packages.NeedModule,
+	}
+}
+
+// SplitTags split each of tags as a comma or space separated list of build tags,
+// empty and duplicate tags are dropped, so is wireinject which is always set
+func SplitTags(tags ...string) []string {
+	var list []string
+	for _, s := range tags {
+		for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if tag != "wireinject" && !slices.Contains(list, tag) {
+				list = append(list, tag)
+			}
+		}
 	}
-}()
+	return list
+}
@@ -0,0 +1,32 @@
+package conf
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitTags(t *testing.T) {
+	cases := []struct {
+		tags []string
+		want []string
+	}{
+		{[]string{"a,b"}, []string{"a", "b"}},
+		{[]string{"a b", "b,c"}, []string{"a", "b", "c"}},
+		{[]string{"", "wireinject,a"}, []string{"a"}},
+		{nil, nil},
+	}
+	for _, c := range cases {
+		if got := SplitTags(c.tags...); !slices.Equal(got, c.want) {
+			t.Errorf("SplitTags(%q) = %q, want %q", c.tags, got, c.want)
+		}
+	}
+}
+
+func TestNewTags(t *testing.T) {
+	if got := New("", "a,b").BuildFlags; !slices.Equal(got, []string{"-tags=wireinject,a,b"}) {
+		t.Errorf("unexpected build flags %q", got)
+	}
+	if got := New("", "").BuildFlags; !slices.Equal(got, []string{"-tags=wireinject"}) {
+		t.Errorf("unexpected build flags %q", got)
+	}
+}
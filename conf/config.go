@@ -0,0 +1,122 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileName is the name of the project level configuration file
+const FileName = "autowire.json"
+
+// Config is the project level configuration, usually read from autowire.json at the module root,
+// patterns are globs in which * matches any sequence of characters including /
+type Config struct {
+	// name patterns of provider functions, default to New*
+	Providers []string `json:"providers,omitempty"`
+	// package path patterns where providers are searched, all packages are included if empty
+	Include []string `json:"include,omitempty"`
+	// package path patterns where providers are never searched, e.g. */testutil/*
+	Exclude []string `json:"exclude,omitempty"`
+	// search providers only in the main module
+	ModuleOnly bool `json:"moduleOnly,omitempty"`
+	// never search providers in the standard library
+	ExcludeStd bool `json:"excludeStd,omitempty"`
+	// preferred provider by type, e.g. "*database/sql.DB": "github.com/x/db.NewPostgres"
+	Prefer map[string]string `json:"prefer,omitempty"`
+	// additional build tags, wireinject is always set
+	Tags []string `json:"tags,omitempty"`
+	// whether to rewrite source files, default to true
+	RewriteSource *bool `json:"rewriteSource,omitempty"`
+}
+
+// Find walk up from dir to find autowire.json, return empty path if not found,
+// the search stops at the module root containing go.mod, outside a module only dir is searched
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	root, err := moduleRoot(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if root == "" || dir == root {
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// moduleRoot walk up from the absolute dir to find the directory containing go.mod, return empty path if not found
+func moduleRoot(dir string) (string, error) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Read read the config from file path
+func Read(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Rewrite report whether to rewrite source files
+func (c *Config) Rewrite() bool {
+	return c.RewriteSource == nil || *c.RewriteSource
+}
+
+// Match report whether s matches any of patterns, in which * matches any sequence of characters including /
+func Match(patterns []string, s string) bool {
+	for _, p := range patterns {
+		if matchGlob(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(pattern, s string) bool {
+	prefix, rest, found := strings.Cut(pattern, "*")
+	if !found {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, prefix) {
+		return false
+	}
+	s = s[len(prefix):]
+	// try every possible length matched by *
+	for i := 0; i <= len(s); i++ {
+		if matchGlob(rest, s[i:]) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		patterns []string
+		s        string
+		want     bool
+	}{
+		{[]string{"New*"}, "NewFoo", true},
+		{[]string{"New*"}, "OpenFoo", false},
+		{[]string{"New*", "Open*"}, "OpenFoo", true},
+		{[]string{"github.com/x/*"}, "github.com/x/y/z", true},
+		{[]string{"*/mock"}, "github.com/x/mock", true},
+		{[]string{"*/mock"}, "github.com/x/mock/y", false},
+		{nil, "NewFoo", false},
+	}
+	for _, c := range cases {
+		if got := Match(c.patterns, c.s); got != c.want {
+			t.Errorf("Match(%v, %q) = %v, want %v", c.patterns, c.s, got, c.want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := `{"providers": ["Provide*"], "rewriteSource": false}`
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path, err := Find(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != filepath.Join(root, FileName) {
+		t.Fatalf("expecting config found at root, got %q", path)
+	}
+	c, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Rewrite() || len(c.Providers) != 1 || c.Providers[0] != "Provide*" {
+		t.Errorf("unexpected config %+v", c)
+	}
+}
+
+// test config files above the module root are not picked up
+func TestFindAboveModule(t *testing.T) {
+	root := t.TempDir()
+	module := filepath.Join(root, "module")
+	dir := filepath.Join(module, "a")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(module, "go.mod"), []byte("module example.com/a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, start := range []string{dir, module} {
+		path, err := Find(start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path != "" {
+			t.Errorf("expecting no config found from %s, got %q", start, path)
+		}
+	}
+}
@@ -0,0 +1,3 @@
+package args
+
+// this package has injector with arguments for test purpose
@@ -0,0 +1,29 @@
+//go:build wireinject
+
+package args
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/inj/zhao"
+)
+
+type Config struct {
+	Addr string
+}
+
+// NewConfig should not be used as cfg is passed to InitApp
+func NewConfig() *Config {
+	return &Config{}
+}
+
+type App struct{}
+
+func NewApp(cfg *Config, name string, z *zhao.Zhao) *App {
+	return &App{}
+}
+
+// InitApp accept runtime configuration, only zhao is completed by autowire
+func InitApp(cfg *Config, name string) *App {
+	wire.Build(NewApp)
+	return nil
+}
@@ -0,0 +1,3 @@
+package bind
+
+// this package need interface bindings for test purpose
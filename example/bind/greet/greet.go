@@ -0,0 +1,25 @@
+package greet
+
+type Greeter interface {
+	Greet() string
+}
+
+type Hello struct{}
+
+func (*Hello) Greet() string {
+	return "hello"
+}
+
+func NewHello() *Hello {
+	return &Hello{}
+}
+
+type Hi struct{}
+
+func (Hi) Greet() string {
+	return "hi"
+}
+
+func NewHi() Hi {
+	return Hi{}
+}
@@ -0,0 +1,12 @@
+package quiet
+
+// Quiet implements shout.Shouter, but package shout can not import this package
+type Quiet struct{}
+
+func (*Quiet) Shout() string {
+	return "hello"
+}
+
+func NewQuiet() *Quiet {
+	return &Quiet{}
+}
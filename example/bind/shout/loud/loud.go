@@ -0,0 +1,41 @@
+package loud
+
+import "github.com/hauntedness/autowire/example/bind/shout/loud/internal/quiet"
+
+// Level is used by package shout, so this package is loaded but its providers are never required
+const Level = 11
+
+type Loud struct{}
+
+func (*Loud) Shout() string {
+	return "HELLO"
+}
+
+func NewLoud() *Loud {
+	return &Loud{}
+}
+
+// Murmur implements shout.Whisperer, but its provider requires the volume not given by any injector
+type Murmur struct {
+	volume int
+}
+
+func (m *Murmur) Whisper() string {
+	return "hm"
+}
+
+func NewMurmur(volume int) *Murmur {
+	return &Murmur{volume: volume}
+}
+
+type Hush struct {
+	quiet *quiet.Quiet
+}
+
+func (*Hush) Whisper() string {
+	return "sh"
+}
+
+func NewHush() *Hush {
+	return &Hush{quiet: quiet.NewQuiet()}
+}
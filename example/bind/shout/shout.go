@@ -0,0 +1,27 @@
+package shout
+
+import "github.com/hauntedness/autowire/example/bind/shout/loud"
+
+// this package need an implementation declared in a package no injector requires for test purpose
+
+type Shouter interface {
+	Shout() string
+}
+
+type Whisperer interface {
+	Whisper() string
+}
+
+type Speaker struct {
+	Level int
+}
+
+func NewSpeaker(s Shouter) *Speaker {
+	return &Speaker{Level: loud.Level}
+}
+
+type Listener struct{}
+
+func NewListener(w Whisperer) *Listener {
+	return &Listener{}
+}
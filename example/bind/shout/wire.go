@@ -0,0 +1,20 @@
+//go:build wireinject
+
+package shout
+
+import (
+	"github.com/google/wire"
+)
+
+// InitSpeaker need Shouter, only *loud.Loud implements it though package loud is never required,
+// *quiet.Quiet is not considered, as its package is internal to loud
+func InitSpeaker() *Speaker {
+	wire.Build(NewSpeaker)
+	return nil
+}
+
+// InitListener need Whisperer, *loud.Hush is bound as loud.NewMurmur requires the volume
+func InitListener() *Listener {
+	wire.Build(NewListener)
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build wireinject
+
+package bind
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/bind/greet"
+	"github.com/hauntedness/autowire/example/param"
+)
+
+type Handler struct{}
+
+func NewHandler(foo param.Foo) *Handler {
+	return &Handler{}
+}
+
+// InitHandler need param.Foo, only *param.FooImpl implements it, so it is bound by autowire
+func InitHandler() *Handler {
+	wire.Build(NewHandler)
+	return nil
+}
+
+type Greeting struct{}
+
+func NewGreeting(g greet.Greeter) *Greeting {
+	return &Greeting{}
+}
+
+// InitGreeting need greet.Greeter, both *greet.Hello and greet.Hi implement it, which is ambiguous
+func InitGreeting() *Greeting {
+	wire.Build(NewGreeting)
+	return nil
+}
+
+// InitBoundGreeting bind the greeter explicitly, autowire completes the implementation
+func InitBoundGreeting() *Greeting {
+	wire.Build(NewGreeting, wire.Bind(new(greet.Greeter), new(*greet.Hello)))
+	return nil
+}
@@ -0,0 +1,3 @@
+package broken
+
+// this package contains broken injectors for test purpose
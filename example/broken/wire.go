@@ -0,0 +1,16 @@
+//go:build wireinject
+
+package broken
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/inj/zhao"
+)
+
+type Tang struct{}
+
+// InitTang is broken on purpose, no provider in wire.Build produce *Tang
+func InitTang() *Tang {
+	wire.Build(zhao.NewZhao)
+	return nil
+}
@@ -0,0 +1,3 @@
+package directive
+
+// this package use //autowire: directives to control provider discovery for test purpose
@@ -0,0 +1,30 @@
+package store
+
+type Cache struct{}
+
+// OpenCache is not named as NewXXX but marked as provider
+//
+//autowire:provider
+func OpenCache() *Cache {
+	return &Cache{}
+}
+
+// NewLegacyCache would be a candidate of Cache without the directive
+//
+//autowire:ignore
+func NewLegacyCache() *Cache {
+	return &Cache{}
+}
+
+type Store struct{}
+
+// NewPostgresStore wins the election though NewMemoryStore requires more beans
+//
+//autowire:primary
+func NewPostgresStore() *Store {
+	return &Store{}
+}
+
+func NewMemoryStore(c *Cache) *Store {
+	return &Store{}
+}
@@ -0,0 +1,20 @@
+//go:build wireinject
+
+package directive
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/directive/store"
+)
+
+type App struct{}
+
+func NewApp(s *store.Store, c *store.Cache) *App {
+	return &App{}
+}
+
+// InitApp is completed with store.OpenCache and store.NewPostgresStore
+func InitApp() *App {
+	wire.Build(NewApp)
+	return nil
+}
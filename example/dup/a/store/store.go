@@ -0,0 +1,7 @@
+package store
+
+type A struct{}
+
+func NewA() *A {
+	return &A{}
+}
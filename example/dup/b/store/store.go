@@ -0,0 +1,7 @@
+package store
+
+type B struct{}
+
+func NewB() *B {
+	return &B{}
+}
@@ -0,0 +1,20 @@
+package dup
+
+import (
+	astore "github.com/hauntedness/autowire/example/dup/a/store"
+	bstore "github.com/hauntedness/autowire/example/dup/b/store"
+)
+
+// this package need providers from packages of the same name for test purpose
+
+type X struct{}
+
+func NewX(a *astore.A) *X {
+	return &X{}
+}
+
+type Y struct{}
+
+func NewY(b *bstore.B) *Y {
+	return &Y{}
+}
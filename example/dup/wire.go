@@ -0,0 +1,18 @@
+//go:build wireinject
+
+package dup
+
+import (
+	"github.com/google/wire"
+)
+
+// InitX and InitY add packages both named store, the alias of the second one is renamed
+func InitX() *X {
+	wire.Build(NewX)
+	return nil
+}
+
+func InitY() *Y {
+	wire.Build(NewY)
+	return nil
+}
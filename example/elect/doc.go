@@ -0,0 +1,3 @@
+package elect
+
+// this package has multiple providers of the same bean for test purpose
@@ -0,0 +1,18 @@
+package store
+
+type Cache struct{}
+
+func NewCache() *Cache {
+	return &Cache{}
+}
+
+type Store struct{}
+
+func NewStore() *Store {
+	return &Store{}
+}
+
+// NewCachedStore is elected over NewStore as it requires more beans
+func NewCachedStore(c *Cache) *Store {
+	return &Store{}
+}
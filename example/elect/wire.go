@@ -0,0 +1,20 @@
+//go:build wireinject
+
+package elect
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/elect/store"
+)
+
+type App struct{}
+
+func NewApp(s *store.Store) *App {
+	return &App{}
+}
+
+// InitApp need a store, which has multiple providers
+func InitApp() *App {
+	wire.Build(NewApp)
+	return nil
+}
@@ -0,0 +1,3 @@
+package generic
+
+// this package has injector requiring instances of generic type for test purpose
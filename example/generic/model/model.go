@@ -0,0 +1,5 @@
+package model
+
+type User struct{}
+
+type Order struct{}
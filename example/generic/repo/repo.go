@@ -0,0 +1,16 @@
+package repo
+
+type DB struct{}
+
+func NewDB() *DB {
+	return &DB{}
+}
+
+// Repo is instantiated for each model, Repo[model.User] and Repo[model.Order] are different beans
+type Repo[T any] struct {
+	db *DB
+}
+
+func NewRepo[T any](db *DB) *Repo[T] {
+	return &Repo[T]{db: db}
+}
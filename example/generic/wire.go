@@ -0,0 +1,33 @@
+//go:build wireinject
+
+package generic
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/generic/model"
+	"github.com/hauntedness/autowire/example/generic/repo"
+)
+
+type Service struct{}
+
+func NewService(users *repo.Repo[model.User], orders *repo.Repo[model.Order]) *Service {
+	return &Service{}
+}
+
+// InitService is completed with repo.NewRepo instantiated for each model
+func InitService() *Service {
+	wire.Build(NewService)
+	return nil
+}
+
+type Audit struct{}
+
+func NewAudit(users *repo.Repo[model.User]) *Audit {
+	return &Audit{}
+}
+
+// InitAudit is complete, the instance in wire.Build is understood
+func InitAudit() *Audit {
+	wire.Build(NewAudit, repo.NewDB, repo.NewRepo[model.User])
+	return nil
+}
@@ -0,0 +1,3 @@
+package incompatible
+
+// this package has injector whose providers return error it can not propagate for test purpose
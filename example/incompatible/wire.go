@@ -0,0 +1,20 @@
+//go:build wireinject
+
+package incompatible
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/resource/db"
+)
+
+type Service struct{}
+
+func NewService(c *db.Conn) *Service {
+	return &Service{}
+}
+
+// InitService is broken on purpose, db.NewConn returns error but InitService does not
+func InitService() *Service {
+	wire.Build(NewService)
+	return nil
+}
@@ -0,0 +1,3 @@
+package incomplete
+
+// this package contains incomplete injectors for test purpose
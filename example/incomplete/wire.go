@@ -0,0 +1,14 @@
+//go:build wireinject
+
+package incomplete
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/inj/liu"
+)
+
+// InitLiu lacks providers on purpose, it is completed by autowire in tests without rewriting source
+func InitLiu() *liu.Liu {
+	wire.Build(liu.NewLiu)
+	return nil
+}
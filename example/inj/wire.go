@@ -19,6 +19,6 @@ func NewShu(liu *liu.Liu, zhao *zhao.Zhao) *Shu {
 
 //go:generate autowire
 func InitShu() *Shu {
-	wire.Build(NewShu, liu.NewLiu, zhao.NewZhao, guan.NewGuan, zhang.NewZhang, yanyan.NewYanYan)
+	wire.Build(NewShu, liu.NewLiu, guan.NewGuan, zhang.NewZhang, yanyan.NewYanYan, zhao.NewZhao)
 	return nil
 }
@@ -11,3 +11,7 @@ type FooImpl struct {
 func (*FooImpl) Do(text string) error {
 	return nil
 }
+
+func NewFooImpl() *FooImpl {
+	return &FooImpl{Name: "foo"}
+}
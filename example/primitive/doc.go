@@ -0,0 +1,3 @@
+package primitive
+
+// this package has injectors whose providers require basic types for test purpose
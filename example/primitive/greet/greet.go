@@ -0,0 +1,8 @@
+package greet
+
+type Greeter struct{}
+
+// NewGreeter can only be used when the injector gives the greeting
+func NewGreeter(greeting string) *Greeter {
+	return &Greeter{}
+}
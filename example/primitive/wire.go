@@ -0,0 +1,45 @@
+//go:build wireinject
+
+package primitive
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/inj/liu"
+	"github.com/hauntedness/autowire/example/primitive/greet"
+)
+
+type Hall struct{}
+
+func NewHall(l *liu.Liu) *Hall {
+	return &Hall{}
+}
+
+// InitHall pass the name, so liu.NewLiu2 requiring more beans is chosen
+func InitHall(name string) *Hall {
+	wire.Build(NewHall)
+	return nil
+}
+
+// InitHallValue give the name by wire.Value
+func InitHallValue() *Hall {
+	wire.Build(NewHall, wire.Value("liu bei"))
+	return nil
+}
+
+// InitHallDefault has no name, so liu.NewLiu is chosen
+func InitHallDefault() *Hall {
+	wire.Build(NewHall)
+	return nil
+}
+
+type Lobby struct{}
+
+func NewLobby(g *greet.Greeter) *Lobby {
+	return &Lobby{}
+}
+
+// InitLobby is broken on purpose, greet.NewGreeter requires the greeting
+func InitLobby() *Lobby {
+	wire.Build(NewLobby)
+	return nil
+}
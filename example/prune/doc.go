@@ -0,0 +1,3 @@
+package prune
+
+// this package has injector with unused providers in wire.Build for test purpose
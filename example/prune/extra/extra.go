@@ -0,0 +1,7 @@
+package extra
+
+type Extra struct{}
+
+func NewExtra() *Extra {
+	return &Extra{}
+}
@@ -0,0 +1,7 @@
+package noise
+
+type Noise struct{}
+
+func NewNoise() *Noise {
+	return &Noise{}
+}
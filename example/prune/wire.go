@@ -0,0 +1,30 @@
+//go:build wireinject
+
+package prune
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/inj/zhao"
+	"github.com/hauntedness/autowire/example/prune/extra"
+	"github.com/hauntedness/autowire/example/prune/noise"
+)
+
+var NoiseSet = wire.NewSet(noise.NewNoise)
+
+type Shop struct{}
+
+func NewShop(z *zhao.Zhao) *Shop {
+	return &Shop{}
+}
+
+type Clerk struct{}
+
+func NewClerk() *Clerk {
+	return &Clerk{}
+}
+
+// InitShop has providers no longer needed, NewClerk and extra.NewExtra are removed by -prune, NoiseSet by -prune-all
+func InitShop() *Shop {
+	wire.Build(NewShop, zhao.NewZhao, NewClerk, extra.NewExtra, NoiseSet)
+	return nil
+}
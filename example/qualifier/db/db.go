@@ -0,0 +1,15 @@
+package db
+
+type DB struct {
+	dsn string
+}
+
+//autowire:qualifier primary
+func NewPrimaryDB() *DB {
+	return &DB{dsn: "primary"}
+}
+
+//autowire:qualifier replica
+func NewReplicaDB() *DB {
+	return &DB{dsn: "replica"}
+}
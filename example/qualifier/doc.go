@@ -0,0 +1,3 @@
+package qualifier
+
+// this package has providers of the same type distinguished by //autowire:qualifier for test purpose
@@ -0,0 +1,23 @@
+//go:build wireinject
+
+package qualifier
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/qualifier/db"
+)
+
+type Repo struct{}
+
+// NewRepo read from the replica
+//
+//autowire:qualifier db=replica
+func NewRepo(db *db.DB) *Repo {
+	return &Repo{}
+}
+
+// InitRepo is completed with db.NewReplicaDB rather than db.NewPrimaryDB
+func InitRepo() *Repo {
+	wire.Build(NewRepo)
+	return nil
+}
@@ -0,0 +1,27 @@
+package db
+
+type DB struct{}
+
+func (db *DB) Close() {}
+
+func NewDB() (*DB, func(), error) {
+	db := &DB{}
+	return db, db.Close, nil
+}
+
+type Logger struct{}
+
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+// NewMemDB can not fail, it is used by injectors not returning error
+func NewMemDB() *DB {
+	return &DB{}
+}
+
+type Conn struct{}
+
+func NewConn(db *DB) (*Conn, error) {
+	return &Conn{}, nil
+}
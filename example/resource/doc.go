@@ -0,0 +1,3 @@
+package resource
+
+// this package has providers returning cleanup function and error for test purpose
@@ -0,0 +1,32 @@
+//go:build wireinject
+
+package resource
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/resource/db"
+)
+
+type App struct{}
+
+func NewApp(d *db.DB, l *db.Logger) (*App, error) {
+	return &App{}, nil
+}
+
+// InitApp is completed with db.NewDB and db.NewLogger
+func InitApp() (*App, func(), error) {
+	wire.Build(NewApp)
+	return nil, nil, nil
+}
+
+type Repo struct{}
+
+func NewRepo(d *db.DB) *Repo {
+	return &Repo{}
+}
+
+// InitRepo can not fail, so db.NewMemDB is chosen rather than db.NewDB
+func InitRepo() *Repo {
+	wire.Build(NewRepo)
+	return nil
+}
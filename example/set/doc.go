@@ -0,0 +1,3 @@
+package set
+
+// this package use provider set in wire.Build for test purpose
@@ -0,0 +1,24 @@
+package store
+
+import "github.com/google/wire"
+
+// ProviderSet provide the store and its cache, the config is left to the injector
+var ProviderSet = wire.NewSet(NewStore, wire.NewSet(NewCache))
+
+type Config struct{}
+
+func NewConfig() *Config {
+	return &Config{}
+}
+
+type Cache struct{}
+
+func NewCache() *Cache {
+	return &Cache{}
+}
+
+type Store struct{}
+
+func NewStore(cfg *Config, cache *Cache) *Store {
+	return &Store{}
+}
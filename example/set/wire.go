@@ -0,0 +1,21 @@
+//go:build wireinject
+
+package set
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/inj/zhao"
+	"github.com/hauntedness/autowire/example/set/store"
+)
+
+type Service struct{}
+
+func NewService(s *store.Store, z *zhao.Zhao) *Service {
+	return &Service{}
+}
+
+// InitService mix provider set and function, the config and zhao are completed by autowire
+func InitService() *Service {
+	wire.Build(NewService, store.ProviderSet)
+	return nil
+}
@@ -0,0 +1,3 @@
+package structs
+
+// this package need struct provider for test purpose
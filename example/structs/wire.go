@@ -0,0 +1,28 @@
+//go:build wireinject
+
+package structs
+
+import (
+	"github.com/google/wire"
+	"github.com/hauntedness/autowire/example/inj/liu"
+	"github.com/hauntedness/autowire/example/inj/zhao"
+)
+
+// Server has no provider function, all its fields are beans except addr
+type Server struct {
+	Liu  *liu.Liu
+	Zhao *zhao.Zhao
+	addr string `wire:"-"`
+}
+
+type App struct{}
+
+func NewApp(s *Server) *App {
+	return &App{}
+}
+
+// InitApp can be completed only when struct provider is enabled
+func InitApp() *App {
+	wire.Build(NewApp)
+	return nil
+}
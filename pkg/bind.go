@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"cmp"
+	"go/token"
+	"go/types"
+	"slices"
+	"strings"
+
+	"github.com/hauntedness/autowire/pkg/comm"
+)
+
+// bindFor find the implementation of the interface bean of e, and bind it with wire.Bind.
+// If the injector already provide an implementation, only the binding is returned,
+// else the only provider whose output implements the interface is returned with the binding.
+// Providers are screened as in elect and narrowed by //autowire:primary and Prefer,
+// they are searched in the packages loaded so far and the ones of the main module the injector can import.
+// Multiple implementations are reported as AmbiguousBindingError.
+func (di *DIContext) bindFor(e *Election) (bind *comm.Provider, provider *comm.Provider, err error) {
+	inj, bean := e.Injector, e.Bean
+	iface, ok := bean.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, nil, &ProviderNotFoundError{Injector: inj.String(), Bean: bean.String()}
+	}
+	// implementations provided by injector itself
+	var owned []*comm.Bean
+	for _, b := range inj.Provided() {
+		if implements(b, bean, iface) {
+			owned = append(owned, b)
+		}
+	}
+	switch len(owned) {
+	case 0:
+	case 1:
+		return comm.NewBind(bean.Type(), owned[0].Type(), inj.Package(), token.NoPos), nil, nil
+	default:
+		candidates := make([]string, 0, len(owned))
+		for _, b := range owned {
+			candidates = append(candidates, b.String())
+		}
+		return nil, nil, &AmbiguousBindingError{Injector: inj.String(), Bean: bean.String(), Candidates: candidates}
+	}
+	// implementations may be declared in packages no injector required yet, so they are loaded first
+	for _, path := range di.implementers(bean, iface) {
+		if importable(inj.Package(), path) {
+			di.requirePackage(path)
+		}
+	}
+	var found []*comm.Provider
+	for _, p := range di.providers {
+		if !p.Generic() && implements(p.Provide(), bean, iface) && importable(inj.Package(), p.Package()) {
+			found = append(found, p)
+		}
+	}
+	slices.SortFunc(found, func(a, b *comm.Provider) int {
+		return cmp.Compare(a.String(), b.String())
+	})
+	var screen screening
+	impls := slices.DeleteFunc(found, func(p *comm.Provider) bool {
+		return !screen.pass(di, e, p)
+	})
+	var prefer map[string]string
+	if c, ok := di.conf.(*DefaultProcessConfigurer); ok {
+		prefer = c.Prefer
+	}
+	impls = explicitChoice(e, impls, prefer)
+	switch len(impls) {
+	case 0:
+		if err := screen.err(e); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, &ProviderNotFoundError{Injector: inj.String(), Bean: bean.String()}
+	case 1:
+		p := impls[0]
+		return comm.NewBind(bean.Type(), p.Provide().Type(), inj.Package(), token.NoPos), p, nil
+	default:
+		candidates := make([]string, 0, len(impls))
+		for _, p := range impls {
+			candidates = append(candidates, p.FullName())
+		}
+		return nil, nil, &AmbiguousBindingError{Injector: inj.String(), Bean: bean.String(), Candidates: candidates}
+	}
+}
+
+// implementers return the sorted paths of allowed packages of the main module
+// declaring functions whose first result is a concrete type implementing the interface bean,
+// the result is cached by the interface type as the packages are scanned
+func (di *DIContext) implementers(bean *comm.Bean, iface *types.Interface) []string {
+	if paths, ok := di.impls.At(bean.Type()).([]string); ok {
+		return paths
+	}
+	var paths []string
+	for path, pkg := range di.universe {
+		if pkg.Types == nil || pkg.Module == nil || !pkg.Module.Main || !di.allowed(path) {
+			continue
+		}
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			fn, ok := scope.Lookup(name).(*types.Func)
+			if !ok {
+				continue
+			}
+			results := fn.Type().(*types.Signature).Results()
+			if results.Len() == 0 {
+				continue
+			}
+			typ := results.At(0).Type()
+			if !types.Identical(typ, bean.Type()) && !types.IsInterface(typ) && types.Implements(typ, iface) {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	slices.Sort(paths)
+	di.impls.Set(bean.Type(), paths)
+	return paths
+}
+
+// importable report whether package path can be imported by package from, that is path has no internal element
+// or from is in the tree rooted at the parent of the last internal element
+func importable(from, path string) bool {
+	var parent string
+	switch i := strings.LastIndex(path, "/internal/"); {
+	case strings.HasSuffix(path, "/internal"):
+		parent = strings.TrimSuffix(path, "/internal")
+	case i >= 0:
+		parent = path[:i]
+	case path == "internal" || strings.HasPrefix(path, "internal/"):
+		// internal packages of the standard library
+		return false
+	default:
+		return true
+	}
+	return from == parent || strings.HasPrefix(from, parent+"/")
+}
+
+// implements report whether b is a concrete type implementing the interface bean
+func implements(b *comm.Bean, bean *comm.Bean, iface *types.Interface) bool {
+	if b.Identical(bean) || types.IsInterface(b.Type()) {
+		return false
+	}
+	return types.Implements(b.Type(), iface)
+}
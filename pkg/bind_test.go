@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDIContext_Bind(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	path := "github.com/hauntedness/autowire/example/bind"
+	err := di.Process(path)
+	// InitGreeting is ambiguous
+	var ambiguous *AmbiguousBindingError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expecting AmbiguousBindingError, got %v", err)
+	}
+	if len(ambiguous.Candidates) != 2 {
+		t.Fatalf("expecting 2 candidates, got %v", ambiguous.Candidates)
+	}
+	// InitHandler is bound by autowire
+	var sb strings.Builder
+	if err := di.Diff(&sb); err != nil {
+		t.Fatal(err)
+	}
+	diff := sb.String()
+	for _, want := range []string{
+		"+	wire.Build(NewHandler, wire.Bind(new(param.Foo), new(*param.FooImpl)), param.NewFooImpl)",
+		"+	wire.Build(NewGreeting, wire.Bind(new(greet.Greeter), new(*greet.Hello)), greet.NewHello)",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("expecting diff contains %q, got:\n%s", want, diff)
+		}
+	}
+}
+
+// test implementations are found in packages no injector has required, but not in internal packages the injector can not import
+func TestDIContext_BindUnrequired(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/bind/shout"); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := di.Diff(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "+	wire.Build(NewSpeaker, wire.Bind(new(Shouter), new(*loud.Loud)), loud.NewLoud)"
+	if diff := sb.String(); !strings.Contains(diff, want) || strings.Contains(diff, "quiet.NewQuiet") {
+		t.Errorf("expecting diff contains %q and nothing of package quiet, got:\n%s", want, diff)
+	}
+}
+
+// test implementations whose providers require primitives not given by the injector are not bound
+func TestDIContext_BindPrimitive(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Resolve("github.com/hauntedness/autowire/example/bind/shout"); err != nil {
+		t.Fatal(err)
+	}
+	elections := di.Elections("shout.Whisperer")
+	if len(elections) != 1 {
+		t.Fatalf("expecting 1 election, got %d", len(elections))
+	}
+	e := elections[0]
+	if e.Winner == nil || !strings.Contains(e.Note, "loud.NewHush") {
+		t.Fatalf("expecting loud.NewHush bound, got %v: %s", e.Winner, e.Note)
+	}
+	if len(e.Eliminated) != 1 || e.Eliminated[0].Provider.Name() != "NewMurmur" {
+		t.Errorf("expecting loud.NewMurmur eliminated, got %v", e.Eliminated)
+	}
+	// the packages are scanned once for Shouter and Whisperer
+	if di.impls.Len() != 2 {
+		t.Errorf("expecting implementers cached for 2 interfaces, got %d", di.impls.Len())
+	}
+}
+
+func Test_importable(t *testing.T) {
+	cases := []struct {
+		from, path string
+		want       bool
+	}{
+		{"example.com/a", "example.com/b", true},
+		{"example.com/a", "internal/abi", false},
+		{"example.com/a", "os", true},
+		{"example.com/a/b", "example.com/a/internal/c", true},
+		{"example.com/a", "example.com/a/internal", true},
+		{"example.com/x", "example.com/a/internal/c", false},
+		{"example.com/a/internal/b", "example.com/a/internal/b/internal/c", true},
+		{"example.com/a/d", "example.com/a/internal/b/internal/c", false},
+	}
+	for _, c := range cases {
+		if got := importable(c.from, c.path); got != c.want {
+			t.Errorf("importable(%q, %q) = %v, want %v", c.from, c.path, got, c.want)
+		}
+	}
+}
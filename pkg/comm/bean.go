@@ -16,17 +16,49 @@ const (
 type Bean struct {
 	pkg string
 	typ types.Type
+	// distinguish beans of the same type, marked by //autowire:qualifier
+	qualifier string
 }
 
 func (b *Bean) PkgPath() string {
 	return b.pkg
 }
 
+func (b *Bean) Type() types.Type {
+	return b.typ
+}
+
+func (b *Bean) Qualifier() string {
+	return b.qualifier
+}
+
+// Id identify the bean by its type and qualifier
+func (b *Bean) Id() BeanId {
+	if b.qualifier == "" {
+		return b.String()
+	}
+	return b.String() + " qualified by " + b.qualifier
+}
+
 func (b *Bean) String() string {
 	// TODO what to do for pointer kind?
 	return b.typ.String()
 }
 
+// Primitive report whether the bean is a basic or composite type without name, e.g. string, []byte or func(),
+// autowire never search provider for it, it should be an argument of the injector or given by wire.Value
+func (b *Bean) Primitive() bool {
+	typ := types.Unalias(b.typ)
+	if ptr, ok := typ.(*types.Pointer); ok {
+		typ = types.Unalias(ptr.Elem())
+	}
+	switch typ.(type) {
+	case *types.Named, *types.Interface, *types.Struct, *types.TypeParam:
+		return false
+	}
+	return true
+}
+
 func (b *Bean) Kind() BeanKind {
 	return kindOf(b.typ)
 }
@@ -46,12 +78,8 @@ func kindOf(typ types.Type) BeanKind {
 	}
 }
 
+// Identical report whether b and other are the same type,
+// all packages are loaded at once, so types are compared by identity
 func (b *Bean) Identical(other *Bean) bool {
-	if types.Identical(b.typ, other.typ) {
-		return true
-	}
-	if b.String() == other.String() {
-		return true
-	}
-	return false
+	return types.Identical(b.typ, other.typ)
 }
@@ -0,0 +1,22 @@
+package comm
+
+import "fmt"
+
+// OutputNotProvidedError is reported when no provider in wire.Build produce the result of the injector
+type OutputNotProvidedError struct {
+	Injector string
+	Bean     string
+}
+
+func (e *OutputNotProvidedError) Error() string {
+	return fmt.Sprintf("injector %s need %v, the corresponding provider should be in wire.Build func", e.Injector, e.Bean)
+}
+
+// RenameError is reported when no alias can be chosen for an import path
+type RenameError struct {
+	Path string
+}
+
+func (e *RenameError) Error() string {
+	return fmt.Sprintf("fail to rename path after trying many times: %s", e.Path)
+}
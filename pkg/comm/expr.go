@@ -0,0 +1,178 @@
+package comm
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"go/types"
+	"slices"
+	"strconv"
+
+	"github.com/dave/dst"
+)
+
+const wirePath = "github.com/google/wire"
+
+var wirePkg = types.NewPackage(wirePath, "wire")
+
+// exprBuilder build syntax nodes for providers, all referenced packages are recorded in imports
+type exprBuilder struct {
+	imports map[path]*types.Package
+}
+
+func newExprBuilder() *exprBuilder {
+	return &exprBuilder{imports: map[path]*types.Package{}}
+}
+
+// packages report the imported packages sorted by path
+func (eb *exprBuilder) packages() []*types.Package {
+	pkgs := make([]*types.Package, 0, len(eb.imports))
+	for _, pkg := range eb.imports {
+		pkgs = append(pkgs, pkg)
+	}
+	slices.SortFunc(pkgs, func(a, b *types.Package) int {
+		return cmp.Compare(a.Path(), b.Path())
+	})
+	return pkgs
+}
+
+// providerExpr build the argument of wire.Build for p
+func (eb *exprBuilder) providerExpr(p *Provider) (dst.Expr, error) {
+	switch p.kind {
+	case FuncProvider:
+		return eb.funcExpr(p)
+	case BindProvider:
+		iface, err := eb.typeExpr(p.iface)
+		if err != nil {
+			return nil, err
+		}
+		impl, err := eb.typeExpr(p.impl)
+		if err != nil {
+			return nil, err
+		}
+		return eb.wireCall("Bind", eb.newCall(iface), eb.newCall(impl)), nil
+	case StructProvider:
+		typ, err := eb.typeExpr(p.named)
+		if err != nil {
+			return nil, err
+		}
+		args := []dst.Expr{eb.newCall(typ)}
+		if p.all {
+			args = append(args, &dst.BasicLit{Kind: token.STRING, Value: strconv.Quote("*")})
+		} else {
+			for _, f := range p.fields {
+				args = append(args, &dst.BasicLit{Kind: token.STRING, Value: strconv.Quote(f.Name())})
+			}
+		}
+		return eb.wireCall("Struct", args...), nil
+	case ValueProvider:
+		value := dst.Clone(p.value).(dst.Expr)
+		if p.iface != nil {
+			iface, err := eb.typeExpr(p.iface)
+			if err != nil {
+				return nil, err
+			}
+			return eb.wireCall("InterfaceValue", eb.newCall(iface), value), nil
+		}
+		return eb.wireCall("Value", value), nil
+	default:
+		return nil, fmt.Errorf("can not express provider in wire.Build: %s", p)
+	}
+}
+
+// ident create an identifier with import path, which is resolved to a qualified identifier when restored
+func (eb *exprBuilder) ident(pkg *types.Package, name string) *dst.Ident {
+	ident := dst.NewIdent(name)
+	if pkg != nil {
+		eb.imports[pkg.Path()] = pkg
+		ident.Path = pkg.Path()
+	}
+	return ident
+}
+
+// funcExpr create the function of p, explicitly instantiated if p is an instance, e.g. repo.NewRepo[model.User]
+func (eb *exprBuilder) funcExpr(p *Provider) (dst.Expr, error) {
+	return eb.instantiate(eb.ident(p.fn.Pkg(), p.fn.Name()), p.targs)
+}
+
+// instantiate create x[targs...], x itself if there is no type argument
+func (eb *exprBuilder) instantiate(x dst.Expr, targs []types.Type) (dst.Expr, error) {
+	indices := make([]dst.Expr, 0, len(targs))
+	for _, t := range targs {
+		expr, err := eb.typeExpr(t)
+		if err != nil {
+			return nil, err
+		}
+		indices = append(indices, expr)
+	}
+	switch len(indices) {
+	case 0:
+		return x, nil
+	case 1:
+		return &dst.IndexExpr{X: x, Index: indices[0]}, nil
+	default:
+		return &dst.IndexListExpr{X: x, Indices: indices}, nil
+	}
+}
+
+// wireCall create call expression wire.name(args...)
+func (eb *exprBuilder) wireCall(name string, args ...dst.Expr) *dst.CallExpr {
+	return &dst.CallExpr{Fun: eb.ident(wirePkg, name), Args: args}
+}
+
+// newCall create call expression new(typ)
+func (eb *exprBuilder) newCall(typ dst.Expr) *dst.CallExpr {
+	return &dst.CallExpr{Fun: dst.NewIdent("new"), Args: []dst.Expr{typ}}
+}
+
+// typeExpr create the syntax of typ
+func (eb *exprBuilder) typeExpr(typ types.Type) (dst.Expr, error) {
+	switch t := typ.(type) {
+	case *types.Named:
+		if t.Obj().Pkg() == nil {
+			// universe types like error
+			return dst.NewIdent(t.Obj().Name()), nil
+		}
+		targs := make([]types.Type, 0, t.TypeArgs().Len())
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			targs = append(targs, t.TypeArgs().At(i))
+		}
+		return eb.instantiate(eb.ident(t.Obj().Pkg(), t.Obj().Name()), targs)
+	case *types.Basic:
+		return dst.NewIdent(t.Name()), nil
+	case *types.Pointer:
+		elem, err := eb.typeExpr(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &dst.StarExpr{X: elem}, nil
+	case *types.Slice:
+		elem, err := eb.typeExpr(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &dst.ArrayType{Elt: elem}, nil
+	case *types.Array:
+		elem, err := eb.typeExpr(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		length := &dst.BasicLit{Kind: token.INT, Value: strconv.FormatInt(t.Len(), 10)}
+		return &dst.ArrayType{Len: length, Elt: elem}, nil
+	case *types.Map:
+		key, err := eb.typeExpr(t.Key())
+		if err != nil {
+			return nil, err
+		}
+		value, err := eb.typeExpr(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return &dst.MapType{Key: key, Value: value}, nil
+	case *types.Interface:
+		if t.Empty() {
+			return &dst.InterfaceType{Methods: &dst.FieldList{}}, nil
+		}
+	}
+	return nil, fmt.Errorf("can not express type %s", typ)
+}
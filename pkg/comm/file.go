@@ -2,7 +2,6 @@ package comm
 
 import (
 	"cmp"
-	"fmt"
 	"go/token"
 	"go/types"
 	"log/slog"
@@ -24,6 +23,8 @@ type WireFile struct {
 	pkg       string
 	file      *dst.File
 	injectors map[injectId]*Injector
+	sortArgs  bool   // whether to sort all arguments of wire.Build rather than only the added ones
+	module    string // path of the module containing the file, imports of the module are grouped last
 }
 
 func NewFile(dstFile *dst.File, pkg string) *WireFile {
@@ -40,69 +41,110 @@ func (file *WireFile) AddInjector(list ...*Injector) {
 	}
 }
 
+// SetModule set the path of the module containing the file, see organizeImports
+func (file *WireFile) SetModule(module string) {
+	file.module = module
+}
+
+// SetSortArgs turn on or off sorting all arguments of wire.Build in Refactor, see Injector.Sorted
+func (file *WireFile) SetSortArgs(sort bool) {
+	file.sortArgs = sort
+}
+
 func (file *WireFile) Package() string {
 	return file.pkg
 }
 
+func (file *WireFile) File() *dst.File {
+	return file.file
+}
+
 func (file *WireFile) Imports() []*dst.ImportSpec {
 	return file.file.Imports
 }
 
-func (file *WireFile) Refactor() {
+// sortedInjectors return the injectors of the file in source order
+func (file *WireFile) sortedInjectors() []*Injector {
+	injectors := make([]*Injector, 0, len(file.injectors))
+	for _, inj := range file.injectors {
+		injectors = append(injectors, inj)
+	}
+	slices.SortFunc(injectors, func(a, b *Injector) int {
+		return cmp.Compare(a.fn.Pos(), b.fn.Pos())
+	})
+	return injectors
+}
+
+func (file *WireFile) Refactor() error {
 	origin, current := file.collectImports()
 	defer func() {
 		// rewrite dst file with new imports
 		file.organizeImports(origin, current)
 		slog.Debug("package refactored", "origin imports", origin, "current imports", current)
 	}()
-	// for each refactor pointcut
-	for _, inj := range file.injectors {
+	// for each refactor pointcut, in source order so that imports are aliased the same in every run
+	for _, inj := range file.sortedInjectors() {
 		call := inj.buildCall
 		// if injector need to be refactored
-		if inj.auto && len(inj.origin) < len(inj.providers) {
-			for k, p := range inj.providers {
-				if inj.origin[k] == nil {
-					// resolve import path
-					path := p.fn.Pkg()
-					takeImport(current, path)
-					// resolve build call
-					funcName := p.fn.Name()
-					// add to call expr
-					ident := dst.NewIdent(funcName)
-					ident.Path = path.Path()
-					call.Args = append(call.Args, ident)
+		if !inj.auto {
+			continue
+		}
+		added := map[dst.Expr]*Provider{}
+		for _, p := range inj.Sorted() {
+			if inj.IsOrigin(p) {
+				continue
+			}
+			// resolve build call
+			eb := newExprBuilder()
+			expr, err := eb.providerExpr(p)
+			if err != nil {
+				return err
+			}
+			// resolve import path
+			for _, pkg := range eb.packages() {
+				if pkg.Path() == file.pkg {
+					continue
+				}
+				if err := takeImport(current, pkg); err != nil {
+					return err
 				}
 			}
+			// add to call expr
+			call.Args = append(call.Args, expr)
+			added[expr] = p
+		}
+		if file.sortArgs {
+			inj.sortArgs(added)
+		}
+		if len(added) > 0 || file.sortArgs || inj.pruned {
+			layoutArgs(file.pkg, call)
 		}
 	}
+	return nil
 }
 
+// organizeImports add the imports taken in Refactor goimports-style, the import block is created if missing,
+// imports are grouped into standard library, third party and the module, the groups are separated by blank lines,
+// a new import is placed in order within its group, existing imports and their comments are kept as they are
 func (file *WireFile) organizeImports(origin util.BiMap[path, alias], current util.BiMap[path, alias]) {
-	// find import spec position
-	var importDecl *dst.GenDecl
-declarations:
-	for _, d := range file.file.Decls {
-		if decl, ok := d.(*dst.GenDecl); ok {
-			if _, ok := decl.Specs[0].(*dst.ImportSpec); ok {
-				importDecl = decl
-				break declarations
-			}
-		}
-	}
 	type pair [2]string
 	var pairs []pair
 	for k, v := range current.LMap() {
+		if _, ok := origin.GetByL(k); ok {
+			continue
+		}
 		pairs = append(pairs, pair{k, v})
 	}
+	if len(pairs) == 0 {
+		return
+	}
 	slices.SortFunc(pairs, func(p1, p2 pair) int {
 		return cmp.Compare(p1[0], p2[0])
 	})
+	importDecl := file.importDecl()
 	for _, v := range pairs {
 		path := v[0]
 		alias := v[1]
-		if _, ok := origin.GetByL(path); ok {
-			continue
-		}
 		s := strings.Split(path, "/")
 		var ident *dst.Ident
 		if s[len(s)-1] != alias {
@@ -117,11 +159,78 @@ declarations:
 			},
 			Decs: dst.ImportSpecDecorations{},
 		}
-		importDecl.Specs = append(importDecl.Specs, spec)
+		file.insertImport(importDecl, spec)
 		file.file.Imports = append(file.file.Imports, spec)
 	}
 }
 
+// importDecl find the first import declaration, or create one after the package clause,
+// a single line import is turned into a block so that more imports can be added
+func (file *WireFile) importDecl() *dst.GenDecl {
+	for _, d := range file.file.Decls {
+		if decl, ok := d.(*dst.GenDecl); ok && decl.Tok == token.IMPORT {
+			decl.Lparen = true
+			return decl
+		}
+	}
+	decl := &dst.GenDecl{Tok: token.IMPORT, Lparen: true}
+	decl.Decs.Before = dst.EmptyLine
+	decl.Decs.After = dst.EmptyLine
+	if len(file.file.Decls) > 0 {
+		file.file.Decls[0].Decorations().Before = dst.EmptyLine
+	}
+	file.file.Decls = slices.Insert(file.file.Decls, 0, dst.Decl(decl))
+	return decl
+}
+
+// insertImport insert spec into decl after the imports of the preceding groups and the ones sorted before it in its group,
+// a blank line is put between spec and the adjacent imports of other groups
+func (file *WireFile) insertImport(decl *dst.GenDecl, spec *dst.ImportSpec) {
+	group := file.importGroup(importPath(spec))
+	at := 0
+	for i, s := range decl.Specs {
+		g := file.importGroup(importPath(s.(*dst.ImportSpec)))
+		if g < group || g == group && importPath(s.(*dst.ImportSpec)) < importPath(spec) {
+			at = i + 1
+		}
+	}
+	spec.Decs.Before = dst.NewLine
+	if at > 0 && file.importGroup(importPath(decl.Specs[at-1].(*dst.ImportSpec))) != group {
+		spec.Decs.Before = dst.EmptyLine
+	}
+	if at < len(decl.Specs) {
+		next := decl.Specs[at].(*dst.ImportSpec)
+		switch {
+		case file.importGroup(importPath(next)) != group:
+			next.Decs.Before = dst.EmptyLine
+		case at > 0 && spec.Decs.Before == dst.EmptyLine || at == 0:
+			// spec starts the group instead of next
+			next.Decs.Before = dst.NewLine
+		}
+	}
+	decl.Specs = slices.Insert(decl.Specs, at, dst.Spec(spec))
+}
+
+// importGroup report 0 for the standard library, 1 for third party and 2 for packages in the module of the file
+func (file *WireFile) importGroup(path string) int {
+	if file.module != "" && (path == file.module || strings.HasPrefix(path, file.module+"/")) {
+		return 2
+	}
+	first, _, _ := strings.Cut(path, "/")
+	if !strings.Contains(first, ".") {
+		return 0
+	}
+	return 1
+}
+
+func importPath(spec *dst.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return spec.Path.Value
+	}
+	return path
+}
+
 func (file *WireFile) collectImports() (origin util.BiMap[path, alias], current util.BiMap[path, alias]) {
 	origin = util.NewBiMap[path, alias]()
 	current = util.NewBiMap[path, alias]()
@@ -148,25 +257,29 @@ func (file *WireFile) collectImports() (origin util.BiMap[path, alias], current
 	return origin, current
 }
 
-func takeImport(bm util.BiMap[path, alias], pkg *types.Package) {
+func takeImport(bm util.BiMap[path, alias], pkg *types.Package) error {
 	// rename if conflict package alias
-	alias := renamed(bm, pkg.Path(), pkg.Name())
+	alias, err := renamed(bm, pkg.Path(), pkg.Name())
+	if err != nil {
+		return err
+	}
 	bm.Put(pkg.Path(), alias)
+	return nil
 }
 
 // renamed
-func renamed(bm util.BiMap[path, alias], path path, name alias) alias {
+func renamed(bm util.BiMap[path, alias], path path, name alias) (alias, error) {
 	//
 
 	// if path is already mapped to alias
 	pkgAlias, ok := bm.GetByL(path)
 	if ok {
-		return pkgAlias
+		return pkgAlias, nil
 	}
 
 	// if no one use the this name
 	if _, ok = bm.GetByR(name); !ok {
-		return name
+		return name, nil
 	}
 
 	words := strings.Split(path, "/")
@@ -185,13 +298,13 @@ func renamed(bm util.BiMap[path, alias], path path, name alias) alias {
 	}
 	newAlias := alias(secondLast) + name
 	if _, ok := bm.GetByR(newAlias); !ok {
-		return newAlias
+		return newAlias, nil
 	}
 	for i := range [255]struct{}{} {
 		newAlias = newAlias + strconv.Itoa(i+1)
 		if _, ok := bm.GetByR(newAlias); !ok {
-			return newAlias
+			return newAlias, nil
 		}
 	}
-	panic(fmt.Errorf("fail to rename path after trying many times: %s", path))
+	return "", &RenameError{Path: path}
 }
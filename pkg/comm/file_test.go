@@ -93,7 +93,11 @@ func Test_renamed(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := renamed(tt.args.bm, tt.args.path, tt.args.name); !reflect.DeepEqual(got, tt.want) {
+			got, err := renamed(tt.args.bm, tt.args.path, tt.args.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("renamed() = %v, want %v", got, tt.want)
 			}
 		})
@@ -111,7 +115,9 @@ func TestWireFile_Refactor(t *testing.T) {
 			injector.String(): injector,
 		},
 	}
-	wireFile.Refactor()
+	if err := wireFile.Refactor(); err != nil {
+		t.Fatal(err)
+	}
 }
 
 // newShuInjector load inj.NewShu and package of inj.NewShu
@@ -180,7 +186,10 @@ func newShuInjector(t *testing.T) (injPkg *decorator.Package, guanPkg *decorator
 			}
 		}
 	}
-	beans := injector.Require()
+	beans, err := injector.Require()
+	if err != nil {
+		t.Fatal(err)
+	}
 	for _, bean := range beans {
 		slog.Info("Need", "Id", bean)
 	}
@@ -189,3 +198,55 @@ func newShuInjector(t *testing.T) (injPkg *decorator.Package, guanPkg *decorator
 	}
 	return pkgs[0], pkg_guan[0], injector
 }
+
+func TestWireFile_organizeImports(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "no import",
+			src:  "package a\n\nvar _ = 1\n",
+			want: "package a\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/google/wire\"\n\n\t\"example.com/a/b\"\n)\n\nvar _ = 1\n",
+		},
+		{
+			name: "single line import",
+			src:  "package a\n\nimport \"fmt\"\n",
+			want: "package a\n\nimport (\n\t\"fmt\"\n\n\t\"github.com/google/wire\"\n\n\t\"example.com/a/b\"\n)\n",
+		},
+		{
+			name: "grouped imports with comments",
+			src: "package a\n\nimport (\n\t// fmt is for printing\n\t\"fmt\"\n\t\"os\" // os\n\n" +
+				"\t\"example.com/a/c\" // c\n)\n",
+			want: "package a\n\nimport (\n\t// fmt is for printing\n\t\"fmt\"\n\t\"os\" // os\n\n" +
+				"\t\"github.com/google/wire\"\n\n\t\"example.com/a/b\"\n\t\"example.com/a/c\" // c\n)\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := decorator.Parse(tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file := &WireFile{file: f, module: "example.com/a"}
+			origin := util.NewBiMap[path, alias]()
+			current := util.NewBiMap[path, alias]()
+			for _, spec := range f.Imports {
+				origin.MustPut(importPath(spec), importPath(spec))
+				current.MustPut(importPath(spec), importPath(spec))
+			}
+			current.Put("fmt", "fmt")
+			current.MustPut("github.com/google/wire", "wire")
+			current.MustPut("example.com/a/b", "b")
+			file.organizeImports(origin, current)
+			var sb strings.Builder
+			if err := decorator.Fprint(&sb, f); err != nil {
+				t.Fatal(err)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("organizeImports() got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,379 @@
+package comm
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dave/dst"
+)
+
+const generatedHeader = "// Code generated by autowire. DO NOT EDIT."
+
+// Generate create the file of plain go code for the injectors of file, without wire,
+// beans are constructed in topological order, errors and cleanup functions of providers are handled,
+// other declarations are copied unless they refer to wire, the file is built with the opposite tag !wireinject
+func (file *WireFile) Generate() (*dst.File, error) {
+	injectors := map[string]*Injector{}
+	for _, inj := range file.injectors {
+		injectors[inj.Name()] = inj
+	}
+	out := &dst.File{Name: dst.NewIdent(file.file.Name.Name)}
+	out.Decs.Start.Append(generatedHeader, "\n", "//go:build !wireinject")
+	for _, decl := range file.file.Decls {
+		switch d := decl.(type) {
+		case *dst.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+		case *dst.FuncDecl:
+			if inj := injectors[d.Name.Name]; inj != nil && d.Recv == nil {
+				body, err := newInjectorGen(inj).body()
+				if err != nil {
+					return nil, err
+				}
+				fd := dst.Clone(d).(*dst.FuncDecl)
+				fd.Body = body
+				out.Decls = append(out.Decls, fd)
+				continue
+			}
+		}
+		if refersWire(decl) {
+			slog.Debug("skip declaration referring wire", "file", file.pkg)
+			continue
+		}
+		out.Decls = append(out.Decls, dst.Clone(decl).(dst.Decl))
+	}
+	return out, nil
+}
+
+// refersWire report whether node use any identifier of wire package
+func refersWire(node dst.Node) bool {
+	found := false
+	dst.Inspect(node, func(n dst.Node) bool {
+		if id, ok := n.(*dst.Ident); ok && id.Path == wirePath {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// injectorGen build the body of an injector function
+type injectorGen struct {
+	inj      *Injector
+	eb       *exprBuilder
+	names    map[string]bool     // names used in the function, including package names
+	exprs    map[BeanId]dst.Expr // expressions of the beans constructed
+	visiting map[*Provider]bool  // providers being constructed, to detect cycle
+	stmts    []dst.Stmt
+	cleanups []string // names of cleanup functions in order of construction
+	out      types.Type
+	cleanup  bool // whether the injector return cleanup function
+	err      bool // whether the injector return error
+}
+
+func newInjectorGen(inj *Injector) *injectorGen {
+	g := &injectorGen{
+		inj:      inj,
+		eb:       newExprBuilder(),
+		names:    map[string]bool{"err": true},
+		exprs:    map[BeanId]dst.Expr{},
+		visiting: map[*Provider]bool{},
+	}
+	sig := inj.fn.Type().(*types.Signature)
+	g.out, g.cleanup, g.err = outputOf(sig)
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		g.names[params.At(i).Name()] = true
+	}
+	for _, p := range inj.providers {
+		switch p.kind {
+		case FuncProvider:
+			g.names[p.fn.Pkg().Name()] = true
+		case StructProvider:
+			g.names[p.named.Obj().Pkg().Name()] = true
+		}
+	}
+	return g
+}
+
+func (g *injectorGen) body() (*dst.BlockStmt, error) {
+	if g.out == nil {
+		return nil, fmt.Errorf("injector %s: no result", g.inj)
+	}
+	out, err := g.visit(g.inj.Output())
+	if err != nil {
+		return nil, err
+	}
+	results := []dst.Expr{out}
+	if g.cleanup {
+		results = append(results, g.cleanupFunc())
+	}
+	if g.err {
+		results = append(results, dst.NewIdent("nil"))
+	}
+	g.stmts = append(g.stmts, &dst.ReturnStmt{Results: results})
+	for _, stmt := range g.stmts {
+		// one statement per line even if the original body is written in one line
+		stmt.Decorations().Before = dst.NewLine
+		stmt.Decorations().After = dst.NewLine
+	}
+	return &dst.BlockStmt{List: g.stmts}, nil
+}
+
+// visit construct the providers bean depends on and bean itself, return the expression of bean
+func (g *injectorGen) visit(bean *Bean) (dst.Expr, error) {
+	if expr, ok := g.exprs[bean.Id()]; ok {
+		return dst.Clone(expr).(dst.Expr), nil
+	}
+	p := g.inj.Provider(bean)
+	if p == nil {
+		return nil, fmt.Errorf("injector %s: no provider for %s", g.inj, bean.Id())
+	}
+	if g.visiting[p] {
+		return nil, fmt.Errorf("injector %s: dependency cycle at %s", g.inj, p)
+	}
+	g.visiting[p] = true
+	defer delete(g.visiting, p)
+	switch p.kind {
+	case ArgProvider:
+		g.exprs[bean.Id()] = dst.NewIdent(p.arg.Name())
+	case BindProvider:
+		impl, err := g.visit(p.Require()[0])
+		if err != nil {
+			return nil, err
+		}
+		g.exprs[bean.Id()] = impl
+	case ValueProvider:
+		name := g.varName(p.vtype, nil)
+		value := dst.Clone(p.value).(dst.Expr)
+		g.stmts = append(g.stmts, &dst.AssignStmt{Lhs: []dst.Expr{dst.NewIdent(name)}, Tok: token.DEFINE, Rhs: []dst.Expr{value}})
+		g.exprs[p.Provide().Id()] = dst.NewIdent(name)
+	case StructProvider:
+		if err := g.structCall(p); err != nil {
+			return nil, err
+		}
+	default:
+		if err := g.funcCall(p); err != nil {
+			return nil, err
+		}
+	}
+	expr, ok := g.exprs[bean.Id()]
+	if !ok {
+		// provided by type only, e.g. bean without qualifier provided by a qualified one
+		expr = g.exprs[p.Provide().Id()]
+	}
+	return dst.Clone(expr).(dst.Expr), nil
+}
+
+// funcCall call the provider function p and assign its output to a new variable
+func (g *injectorGen) funcCall(p *Provider) error {
+	args := make([]dst.Expr, 0, len(p.Require()))
+	for _, b := range p.Require() {
+		arg, err := g.visit(b)
+		if err != nil {
+			return err
+		}
+		args = append(args, arg)
+	}
+	out, cleanup, hasErr := p.Provide().Type(), p.HasCleanup(), p.HasErr()
+	if ok, reason := g.inj.Accept(p); !ok {
+		return fmt.Errorf("injector %s: provider %s %s", g.inj, p.FullName(), reason)
+	}
+	name := g.varName(out, p.fn.Pkg())
+	lhs := []dst.Expr{dst.NewIdent(name)}
+	if cleanup {
+		c := g.newName("cleanup")
+		lhs = append(lhs, dst.NewIdent(c))
+		defer func() { g.cleanups = append(g.cleanups, c) }()
+	}
+	if hasErr {
+		lhs = append(lhs, dst.NewIdent("err"))
+	}
+	fun, err := g.eb.funcExpr(p)
+	if err != nil {
+		return err
+	}
+	call := &dst.CallExpr{Fun: fun, Args: args}
+	g.stmts = append(g.stmts, &dst.AssignStmt{Lhs: lhs, Tok: token.DEFINE, Rhs: []dst.Expr{call}})
+	if hasErr {
+		stmt, err := g.errCheck()
+		if err != nil {
+			return err
+		}
+		g.stmts = append(g.stmts, stmt)
+	}
+	g.exprs[p.Provide().Id()] = dst.NewIdent(name)
+	return nil
+}
+
+// structCall construct the struct of provider p with its fields injected
+func (g *injectorGen) structCall(p *Provider) error {
+	typ, err := g.eb.typeExpr(p.named)
+	if err != nil {
+		return err
+	}
+	lit := &dst.CompositeLit{Type: typ}
+	for _, f := range p.fields {
+		value, err := g.visit(p.fromVar(f))
+		if err != nil {
+			return err
+		}
+		kv := &dst.KeyValueExpr{Key: dst.NewIdent(f.Name()), Value: value}
+		kv.Decs.Before = dst.NewLine
+		kv.Decs.After = dst.NewLine
+		lit.Elts = append(lit.Elts, kv)
+	}
+	var rhs dst.Expr = lit
+	if p.ptr {
+		rhs = &dst.UnaryExpr{Op: token.AND, X: lit}
+	}
+	name := g.varName(p.Provide().Type(), p.named.Obj().Pkg())
+	g.stmts = append(g.stmts, &dst.AssignStmt{Lhs: []dst.Expr{dst.NewIdent(name)}, Tok: token.DEFINE, Rhs: []dst.Expr{rhs}})
+	value, pointer := p.beanOf(p.named), p.beanOf(types.NewPointer(p.named))
+	if p.ptr {
+		g.exprs[pointer.Id()] = dst.NewIdent(name)
+		g.exprs[value.Id()] = &dst.StarExpr{X: dst.NewIdent(name)}
+	} else {
+		g.exprs[value.Id()] = dst.NewIdent(name)
+		g.exprs[pointer.Id()] = &dst.UnaryExpr{Op: token.AND, X: dst.NewIdent(name)}
+	}
+	return nil
+}
+
+// errCheck create if err != nil { cleanup(); return zero, nil, err }
+func (g *injectorGen) errCheck() (dst.Stmt, error) {
+	zero, err := g.zeroValue(g.out)
+	if err != nil {
+		return nil, err
+	}
+	var list []dst.Stmt
+	for i := len(g.cleanups) - 1; i >= 0; i-- {
+		list = append(list, &dst.ExprStmt{X: &dst.CallExpr{Fun: dst.NewIdent(g.cleanups[i])}})
+	}
+	results := []dst.Expr{zero}
+	if g.cleanup {
+		results = append(results, dst.NewIdent("nil"))
+	}
+	results = append(results, dst.NewIdent("err"))
+	list = append(list, &dst.ReturnStmt{Results: results})
+	return &dst.IfStmt{
+		Cond: &dst.BinaryExpr{X: dst.NewIdent("err"), Op: token.NEQ, Y: dst.NewIdent("nil")},
+		Body: &dst.BlockStmt{List: list},
+	}, nil
+}
+
+// cleanupFunc create func() { cleanup2(); cleanup() } calling cleanups in reverse order
+func (g *injectorGen) cleanupFunc() dst.Expr {
+	var list []dst.Stmt
+	for i := len(g.cleanups) - 1; i >= 0; i-- {
+		list = append(list, &dst.ExprStmt{X: &dst.CallExpr{Fun: dst.NewIdent(g.cleanups[i])}})
+	}
+	return &dst.FuncLit{
+		Type: &dst.FuncType{Func: true, Params: &dst.FieldList{}},
+		Body: &dst.BlockStmt{List: list},
+	}
+}
+
+// zeroValue create the zero value expression of typ
+func (g *injectorGen) zeroValue(typ types.Type) (dst.Expr, error) {
+	switch t := typ.Underlying().(type) {
+	case *types.Pointer, *types.Interface, *types.Map, *types.Slice, *types.Chan, *types.Signature:
+		return dst.NewIdent("nil"), nil
+	case *types.Basic:
+		switch {
+		case t.Info()&types.IsBoolean != 0:
+			return dst.NewIdent("false"), nil
+		case t.Info()&types.IsString != 0:
+			return &dst.BasicLit{Kind: token.STRING, Value: `""`}, nil
+		default:
+			return &dst.BasicLit{Kind: token.INT, Value: "0"}, nil
+		}
+	}
+	expr, err := g.eb.typeExpr(typ)
+	if err != nil {
+		return nil, err
+	}
+	return &dst.CompositeLit{Type: expr}, nil
+}
+
+// varName choose a variable name for the bean of typ, e.g. guan for *guan.Guan,
+// prefixed by package name if it collides, e.g. guanGuan
+func (g *injectorGen) varName(typ types.Type, pkg *types.Package) string {
+	for {
+		ptr, ok := typ.(*types.Pointer)
+		if !ok {
+			break
+		}
+		typ = ptr.Elem()
+	}
+	name := "v"
+	switch t := typ.(type) {
+	case *types.Named:
+		name = unexport(t.Obj().Name())
+		// prefixed by type arguments, e.g. userRepo for Repo[User]
+		for i := t.TypeArgs().Len() - 1; i >= 0; i-- {
+			arg := t.TypeArgs().At(i)
+			if ptr, ok := arg.(*types.Pointer); ok {
+				arg = ptr.Elem()
+			}
+			if arg, ok := arg.(*types.Named); ok {
+				name = unexport(arg.Obj().Name()) + export(name)
+			}
+		}
+	case *types.Basic:
+		name = unexport(t.Name())
+	}
+	if !g.collides(name) {
+		g.names[name] = true
+		return name
+	}
+	if pkg != nil {
+		if prefixed := pkg.Name() + export(name); !g.collides(prefixed) {
+			g.names[prefixed] = true
+			return prefixed
+		}
+	}
+	return g.newName(name)
+}
+
+// newName return name, or name with a number suffix if it collides
+func (g *injectorGen) newName(name string) string {
+	candidate := name
+	for i := 2; g.collides(candidate); i++ {
+		candidate = name + strconv.Itoa(i)
+	}
+	g.names[candidate] = true
+	return candidate
+}
+
+func (g *injectorGen) collides(name string) bool {
+	return g.names[name] || token.IsKeyword(name) || types.Universe.Lookup(name) != nil
+}
+
+// unexport lower the leading upper case letters, e.g. YanYan to yanYan, HTTPClient to httpClient, DB to db
+func unexport(name string) string {
+	runes := []rune(name)
+	n := 0
+	for n < len(runes) && unicode.IsUpper(runes[n]) {
+		n++
+	}
+	if n > 1 && n < len(runes) {
+		// keep the last upper case letter which starts the next word
+		n--
+	}
+	for i := 0; i < n; i++ {
+		runes[i] = unicode.ToLower(runes[i])
+	}
+	return string(runes)
+}
+
+func export(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
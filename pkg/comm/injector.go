@@ -1,8 +1,10 @@
 package comm
 
 import (
-	"fmt"
+	"cmp"
+	"go/token"
 	"go/types"
+	"slices"
 
 	"github.com/dave/dst"
 )
@@ -16,12 +18,20 @@ type Injector struct {
 	providers map[FuncId]*Provider // all providers after analyzed
 	auto      bool                 // whether autowire can fill up this provider
 	buildCall *dst.CallExpr        // the syntax tree node on which all providers lying
+	args      []*buildArg          // arguments of buildCall with their providers
+	pruned    bool                 // whether some arguments of buildCall are pruned
 }
 
 type BeanId = string
 
-// NewInjector
+// NewInjector create injector with providers in wire.Build, the arguments of fn are added as providers
 func NewInjector(fn *types.Func, origin map[FuncId]*Provider, buildCall *dst.CallExpr, auto bool) *Injector {
+	// injector arguments are provided as is
+	params := fn.Type().(*types.Signature).Params()
+	for i := 0; i < params.Len(); i++ {
+		p := NewArg(params.At(i), fn.Pkg().Path())
+		origin[p.String()] = p
+	}
 	copy := make(map[FuncId]*Provider)
 	for k, p := range origin {
 		copy[k] = p
@@ -39,38 +49,131 @@ func NewInjector(fn *types.Func, origin map[FuncId]*Provider, buildCall *dst.Cal
 //
 //	if provider P in Injector I provide Bean B, then I does not require B
 //	else if No provider provide B, the injector I require B.
-func (inj *Injector) Require() map[BeanId]*Bean {
-	want := &Provider{fn: inj.fn}
-	// here we know that bean is the result of the injector
-	bean, found := want.Provide(), false
+//	arguments of injector function are providers too.
+//	bean with qualifier is only provided by provider with the same qualifier.
+func (inj *Injector) Require() (map[BeanId]*Bean, error) {
+	bean, found := inj.Output(), false
 	required := make(map[BeanId]*Bean)
 	owned := make(map[BeanId]*Bean)
 	for _, p := range inj.providers {
 		for _, b := range p.Require() {
-			required[b.String()] = b
+			required[b.Id()] = b
 		}
-		b := p.Provide()
-		owned[b.String()] = b
-		if !found && b.Identical(bean) {
-			found = true
+		for _, b := range p.ProvideAll() {
+			// a bean without qualifier is satisfied by any bean of the type
+			owned[b.String()] = b
+			owned[b.Id()] = b
+			if !found && b.Identical(bean) {
+				found = true
+			}
 		}
 	}
 	if !found {
-		err := fmt.Errorf("error: the injector func need %v, the corresponding provider should be in wire.Build func", bean.String())
-		panic(err)
+		return nil, &OutputNotProvidedError{Injector: inj.String(), Bean: bean.String()}
 	}
 	for beanId := range required {
 		if _, ok := owned[beanId]; ok {
 			delete(required, beanId)
 		}
 	}
-	return required
+	return required, nil
 }
 
 func (inj *Injector) AddProvider(list ...*Provider) {
 	for _, p := range list {
-		inj.providers[p.fn.String()] = p
+		inj.providers[p.String()] = p
+	}
+}
+
+// Output report the bean returned by the injector
+func (inj *Injector) Output() *Bean {
+	want := &Provider{fn: inj.fn}
+	return want.Provide()
+}
+
+// HasErr report whether the injector function return error, so that providers returning error can be used
+func (inj *Injector) HasErr() bool {
+	_, _, err := outputOf(inj.fn.Type().(*types.Signature))
+	return err
+}
+
+// HasCleanup report whether the injector function return cleanup function, so that providers returning cleanup can be used
+func (inj *Injector) HasCleanup() bool {
+	_, cleanup, _ := outputOf(inj.fn.Type().(*types.Signature))
+	return cleanup
+}
+
+// Accept report whether the error and cleanup function returned by p can be propagated by the injector,
+// reason tells why not
+func (inj *Injector) Accept(p *Provider) (ok bool, reason string) {
+	switch {
+	case p.HasErr() && !inj.HasErr():
+		return false, "returns error, but injector " + inj.Name() + " does not"
+	case p.HasCleanup() && !inj.HasCleanup():
+		return false, "returns cleanup function, but injector " + inj.Name() + " does not"
+	}
+	return true, ""
+}
+
+// Providers report all providers of the injector sorted by String, including the original ones and arguments
+func (inj *Injector) Providers() []*Provider {
+	list := make([]*Provider, 0, len(inj.providers))
+	for _, p := range inj.providers {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b *Provider) int {
+		return cmp.Compare(a.String(), b.String())
+	})
+	return list
+}
+
+// IsOrigin report whether p is written in the original wire.Build call or is an argument of the injector
+func (inj *Injector) IsOrigin(p *Provider) bool {
+	return inj.origin[p.String()] != nil
+}
+
+// Provided report beans provided by the providers of the injector
+func (inj *Injector) Provided() []*Bean {
+	provided := make([]*Bean, 0, len(inj.providers))
+	for _, p := range inj.providers {
+		provided = append(provided, p.Provide())
+	}
+	slices.SortFunc(provided, func(a, b *Bean) int {
+		return cmp.Compare(a.String(), b.String())
+	})
+	return provided
+}
+
+// Added report providers added by autowire, which are not in the original wire.Build call
+func (inj *Injector) Added() []*Provider {
+	added := make([]*Provider, 0, len(inj.providers)-len(inj.origin))
+	for k, p := range inj.providers {
+		if inj.origin[k] == nil {
+			added = append(added, p)
+		}
 	}
+	slices.SortFunc(added, func(a, b *Provider) int {
+		return cmp.Compare(a.String(), b.String())
+	})
+	return added
+}
+
+// Auto report whether autowire can fill up this injector
+func (inj *Injector) Auto() bool {
+	return inj.auto
+}
+
+// SetAuto turn on or off autowire for this injector, e.g. turn off after it fails to resolve
+func (inj *Injector) SetAuto(auto bool) {
+	inj.auto = auto
+}
+
+func (inj *Injector) Pos() token.Pos {
+	return inj.fn.Pos()
+}
+
+func (inj *Injector) Name() string {
+	return inj.fn.Name()
 }
 
 func (inj *Injector) String() string {
@@ -51,7 +51,10 @@ func TestInjector_Need(t *testing.T) {
 			injector.AddProvider(provider)
 		}
 	}
-	beans := injector.Require()
+	beans, err := injector.Require()
+	if err != nil {
+		t.Fatal(err)
+	}
 	for _, bean := range beans {
 		slog.Info("Need", "Id", bean)
 	}
@@ -0,0 +1,138 @@
+package comm
+
+import (
+	"bytes"
+	"cmp"
+	"go/token"
+	"slices"
+	"strings"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/decorator/resolver/guess"
+)
+
+// maxBuildWidth is the width of wire.Build call beyond which each argument is put on its own line
+const maxBuildWidth = 120
+
+// Sorted report all providers of the injector in topological order, a provider comes before the ones it requires,
+// ties are broken by package path and then by name, providers not needed by the output come last,
+// providers in a cycle are appended in the same way
+func (inj *Injector) Sorted() []*Provider {
+	providers := inj.Providers()
+	reachable := inj.Reachable()
+	unused := func(p *Provider) int {
+		if reachable[p] {
+			return 0
+		}
+		return 1
+	}
+	less := func(a, b *Provider) int {
+		return cmp.Or(
+			cmp.Compare(unused(a), unused(b)),
+			cmp.Compare(a.Package(), b.Package()),
+			cmp.Compare(a.Label(nil), b.Label(nil)),
+			cmp.Compare(a.String(), b.String()),
+		)
+	}
+	// number of providers requiring each provider
+	indegree := map[*Provider]int{}
+	deps := map[*Provider][]*Provider{}
+	for _, p := range providers {
+		for _, b := range p.Require() {
+			if dep := inj.Provider(b); dep != nil && dep != p && !slices.Contains(deps[p], dep) {
+				deps[p] = append(deps[p], dep)
+				indegree[dep]++
+			}
+		}
+	}
+	sorted := make([]*Provider, 0, len(providers))
+	done := map[*Provider]bool{}
+	for len(sorted) < len(providers) {
+		var ready []*Provider
+		for _, p := range providers {
+			if !done[p] && indegree[p] == 0 {
+				ready = append(ready, p)
+			}
+		}
+		if len(ready) == 0 {
+			// cycle, take the remaining ones as they are
+			for _, p := range providers {
+				if !done[p] {
+					ready = append(ready, p)
+				}
+			}
+		}
+		next := slices.MinFunc(ready, less)
+		done[next] = true
+		sorted = append(sorted, next)
+		for _, dep := range deps[next] {
+			indegree[dep]--
+		}
+	}
+	return sorted
+}
+
+// sortArgs reorder the arguments of wire.Build by the topological order of their providers,
+// added maps the expressions appended by autowire to their providers
+func (inj *Injector) sortArgs(added map[dst.Expr]*Provider) {
+	rank := map[*Provider]int{}
+	for i, p := range inj.Sorted() {
+		rank[p] = i
+	}
+	exprRank := map[dst.Expr]int{}
+	for expr, p := range added {
+		exprRank[expr] = rank[p]
+	}
+	for _, arg := range inj.args {
+		r := len(rank)
+		for _, p := range arg.providers {
+			if pr, ok := rank[p]; ok {
+				r = min(r, pr)
+			}
+		}
+		exprRank[arg.expr] = r
+	}
+	slices.SortStableFunc(inj.buildCall.Args, func(a, b dst.Expr) int {
+		return cmp.Compare(exprRank[a], exprRank[b])
+	})
+}
+
+// layoutArgs put each argument of wire.Build on its own line if the call is too wide or it is already written so,
+// otherwise all arguments are kept in one line
+func layoutArgs(pkgPath string, call *dst.CallExpr) {
+	multiline := slices.ContainsFunc(call.Args, func(e dst.Expr) bool {
+		return e.Decorations().Before == dst.NewLine || e.Decorations().After == dst.NewLine
+	})
+	if !multiline && callWidth(pkgPath, call) <= maxBuildWidth {
+		return
+	}
+	for i, arg := range call.Args {
+		arg.Decorations().Before = dst.NewLine
+		arg.Decorations().After = dst.None
+		if i == len(call.Args)-1 {
+			arg.Decorations().After = dst.NewLine
+		}
+	}
+}
+
+// callWidth report the width of call printed in one line with a leading tab,
+// packages are qualified by the last element of their paths
+func callWidth(pkgPath string, call *dst.CallExpr) int {
+	flat := dst.Clone(call).(*dst.CallExpr)
+	for _, arg := range flat.Args {
+		arg.Decorations().Before = dst.None
+		arg.Decorations().After = dst.None
+	}
+	file := &dst.File{
+		Name:  dst.NewIdent("p"),
+		Decls: []dst.Decl{&dst.GenDecl{Tok: token.VAR, Specs: []dst.Spec{&dst.ValueSpec{Names: []*dst.Ident{dst.NewIdent("_")}, Values: []dst.Expr{flat}}}}},
+	}
+	buf := &bytes.Buffer{}
+	if err := decorator.NewRestorerWithImports(cmp.Or(pkgPath, "_"), guess.New()).Fprint(buf, file); err != nil {
+		return 0
+	}
+	_, line, _ := strings.Cut(buf.String(), "var _ = ")
+	line, _, _ = strings.Cut(line, "\n")
+	return len("\t") + len(line)
+}
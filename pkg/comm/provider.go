@@ -1,50 +1,360 @@
 package comm
 
 import (
+	"fmt"
+	"go/token"
 	"go/types"
-	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+type ProviderKind int
+
+const (
+	FuncProvider   ProviderKind = iota // a provider function
+	BindProvider                       // wire.Bind(new(Iface), new(Impl))
+	StructProvider                     // wire.Struct(new(T), "*"), provide both T and *T
+	ArgProvider                        // argument of the injector function
+	ValueProvider                      // wire.Value(expr) or wire.InterfaceValue(new(Iface), expr)
 )
 
 type Provider struct {
-	fn *types.Func
+	kind ProviderKind
+	fn   *types.Func
+	// for FuncProvider instantiated from a generic function, the type arguments and the instantiated signature
+	targs []types.Type
+	inst  *types.Signature
+	// for BindProvider, iface is provided and impl is required
+	iface types.Type
+	impl  types.Type
+	// for StructProvider, the struct type and its injected fields, ptr report whether *T is the main output
+	named  *types.Named
+	fields []*types.Var
+	all    bool // all fields not prevented are injected, written as "*"
+	ptr    bool
+	// for ArgProvider, the parameter of injector function
+	arg *types.Var
+	// for ValueProvider, the expression and its type, iface is also set for wire.InterfaceValue
+	value dst.Expr
+	vtype types.Type
+	pkg string    // package where the provider is declared
+	pos token.Pos // position of the declaration
+	// whether the provider wins the election of its output, marked by //autowire:primary
+	primary bool
+	// qualifier of the output and of the params by name, marked by //autowire:qualifier
+	qualifier string
+	params    map[string]string
 }
 
 func NewProvider(fn *types.Func) *Provider {
-	return &Provider{fn: fn}
+	return &Provider{kind: FuncProvider, fn: fn}
+}
+
+// NewInstance create a provider of the generic function fn instantiated with targs, e.g. NewRepo[User],
+// an error is returned if targs do not satisfy the constraints
+func NewInstance(fn *types.Func, targs []types.Type) (*Provider, error) {
+	inst, err := types.Instantiate(nil, fn.Type(), targs, true)
+	if err != nil {
+		return nil, err
+	}
+	return &Provider{kind: FuncProvider, fn: fn, targs: targs, inst: inst.(*types.Signature)}, nil
+}
+
+// NewBind create a provider binding the concrete type impl to the interface type iface,
+// pkg and pos is where the binding is declared, pos can be token.NoPos for generated binding
+func NewBind(iface, impl types.Type, pkg string, pos token.Pos) *Provider {
+	return &Provider{kind: BindProvider, iface: iface, impl: impl, pkg: pkg, pos: pos}
+}
+
+// NewValue create a provider of the value expr of type typ, given by wire.Value(expr),
+// or by wire.InterfaceValue(new(iface), expr) if iface is not nil
+func NewValue(typ types.Type, iface types.Type, expr dst.Expr, pkg string, pos token.Pos) *Provider {
+	return &Provider{kind: ValueProvider, vtype: typ, iface: iface, value: expr, pkg: pkg, pos: pos}
+}
+
+// NewStruct create a provider injecting fields of the struct type named,
+// all fields not prevented by tag wire:"-" are injected if fields is nil,
+// ptr report whether *T rather than T is the main output, though both are provided
+func NewStruct(named *types.Named, fields []*types.Var, ptr bool, pkg string, pos token.Pos) *Provider {
+	all := fields == nil
+	if all {
+		st := named.Underlying().(*types.Struct)
+		for i := 0; i < st.NumFields(); i++ {
+			if reflect.StructTag(st.Tag(i)).Get("wire") == "-" {
+				continue
+			}
+			fields = append(fields, st.Field(i))
+		}
+	}
+	return &Provider{kind: StructProvider, named: named, fields: fields, all: all, ptr: ptr, pkg: pkg, pos: pos}
+}
+
+// NewArg create a provider for the argument of injector function
+func NewArg(arg *types.Var, pkg string) *Provider {
+	return &Provider{kind: ArgProvider, arg: arg, pkg: pkg, pos: arg.Pos()}
+}
+
+func (p *Provider) Primary() bool {
+	return p.primary
+}
+
+func (p *Provider) SetPrimary(primary bool) {
+	p.primary = primary
+}
+
+// SetQualifier set the qualifier of the output of the provider function
+func (p *Provider) SetQualifier(qualifier string) {
+	p.qualifier = qualifier
+}
+
+// SetParamQualifier set the qualifier of the bean required by param of the provider function
+func (p *Provider) SetParamQualifier(param string, qualifier string) {
+	if p.params == nil {
+		p.params = map[string]string{}
+	}
+	p.params[param] = qualifier
+}
+
+func (p *Provider) Kind() ProviderKind {
+	return p.kind
+}
+
+// Generic report whether p is a generic function not instantiated, which can not be used directly
+func (p *Provider) Generic() bool {
+	return p.kind == FuncProvider && p.inst == nil && p.signature().TypeParams().Len() > 0
+}
+
+// TypeArgs report the type arguments of a provider instantiated from a generic function
+func (p *Provider) TypeArgs() []types.Type {
+	return p.targs
+}
+
+// Func report the function of FuncProvider, it is the generic one for instances
+func (p *Provider) Func() *types.Func {
+	return p.fn
+}
+
+// signature is the signature of the provider function, instantiated if p is an instance
+func (p *Provider) signature() *types.Signature {
+	if p.inst != nil {
+		return p.inst
+	}
+	return p.fn.Type().(*types.Signature)
 }
 
 func (p *Provider) Require() []*Bean {
+	switch p.kind {
+	case BindProvider:
+		return []*Bean{p.beanOf(p.impl)}
+	case StructProvider:
+		ret := make([]*Bean, 0, len(p.fields))
+		for _, f := range p.fields {
+			ret = append(ret, p.fromVar(f))
+		}
+		return ret
+	case ArgProvider, ValueProvider:
+		return nil
+	}
 	ret := make([]*Bean, 0, 3)
-	params := p.fn.Type().(*types.Signature).Params()
+	params := p.signature().Params()
 	for i := range make([]struct{}, params.Len()) {
 		v := params.At(i)
 		bean := p.fromVar(v)
+		bean.qualifier = p.params[v.Name()]
 		ret = append(ret, bean)
 	}
 	return ret
 }
 
 func (p *Provider) Provide() *Bean {
-	result := p.fn.Type().(*types.Signature).Results()
+	switch p.kind {
+	case BindProvider:
+		return p.beanOf(p.iface)
+	case StructProvider:
+		if p.ptr {
+			return p.beanOf(types.NewPointer(p.named))
+		}
+		return p.beanOf(p.named)
+	case ArgProvider:
+		return p.fromVar(p.arg)
+	case ValueProvider:
+		if p.iface != nil {
+			return p.beanOf(p.iface)
+		}
+		return p.beanOf(p.vtype)
+	}
+	result := p.signature().Results()
 	bean := p.fromVar(result.At(0))
+	bean.qualifier = p.qualifier
 	return bean
 }
 
+// ProvideAll report all beans provided, struct provider provide both T and *T
+func (p *Provider) ProvideAll() []*Bean {
+	if p.kind == StructProvider {
+		return []*Bean{p.beanOf(p.named), p.beanOf(types.NewPointer(p.named))}
+	}
+	return []*Bean{p.Provide()}
+}
+
+// HasErr report whether the provider function return error as the last result
+func (p *Provider) HasErr() bool {
+	if p.kind != FuncProvider {
+		return false
+	}
+	_, _, err := outputOf(p.signature())
+	return err
+}
+
+// HasCleanup report whether the provider function return cleanup function func()
+func (p *Provider) HasCleanup() bool {
+	if p.kind != FuncProvider {
+		return false
+	}
+	_, cleanup, _ := outputOf(p.signature())
+	return cleanup
+}
+
+// outputOf report the output of a provider or injector function, and whether it return cleanup function and error
+func outputOf(sig *types.Signature) (out types.Type, cleanup bool, err bool) {
+	results := sig.Results()
+	if results.Len() == 0 {
+		return nil, false, false
+	}
+	for i := 1; i < results.Len(); i++ {
+		switch t := results.At(i).Type(); {
+		case types.Identical(t, types.Universe.Lookup("error").Type()):
+			err = true
+		case isCleanup(t):
+			cleanup = true
+		}
+	}
+	return results.At(0).Type(), cleanup, err
+}
+
+func isCleanup(t types.Type) bool {
+	sig, ok := t.(*types.Signature)
+	return ok && sig.Params().Len() == 0 && sig.Results().Len() == 0
+}
+
 func (p *Provider) Name() string {
+	switch p.kind {
+	case BindProvider:
+		return "Bind"
+	case StructProvider:
+		return p.named.Obj().Name()
+	case ArgProvider:
+		return p.arg.Name()
+	case ValueProvider:
+		if p.iface != nil {
+			return "InterfaceValue"
+		}
+		return "Value"
+	}
 	return p.fn.Name()
 }
 
 func (p *Provider) String() string {
+	switch p.kind {
+	case BindProvider:
+		return fmt.Sprintf("wire.Bind(new(%s), new(%s))", p.iface, p.impl)
+	case StructProvider:
+		if p.all {
+			return fmt.Sprintf("wire.Struct(new(%s), %q)", p.named, "*")
+		}
+		names := make([]string, 0, len(p.fields))
+		for _, f := range p.fields {
+			names = append(names, strconv.Quote(f.Name()))
+		}
+		return fmt.Sprintf("wire.Struct(new(%s), %s)", p.named, strings.Join(names, ", "))
+	case ArgProvider:
+		return fmt.Sprintf("argument %s %s", p.arg.Name(), p.arg.Type())
+	case ValueProvider:
+		return p.Label(nil)
+	}
+	if p.inst != nil {
+		return "func " + p.FullName()
+	}
 	return p.fn.String()
 }
 
+// Label is the short form of p as written in wire.Build, types are qualified by qf
+func (p *Provider) Label(qf types.Qualifier) string {
+	switch p.kind {
+	case BindProvider:
+		return fmt.Sprintf("wire.Bind(new(%s), new(%s))", types.TypeString(p.iface, qf), types.TypeString(p.impl, qf))
+	case StructProvider:
+		typ := types.TypeString(p.named, qf)
+		if p.all {
+			return fmt.Sprintf("wire.Struct(new(%s), %q)", typ, "*")
+		}
+		names := make([]string, 0, len(p.fields))
+		for _, f := range p.fields {
+			names = append(names, strconv.Quote(f.Name()))
+		}
+		return fmt.Sprintf("wire.Struct(new(%s), %s)", typ, strings.Join(names, ", "))
+	case ArgProvider:
+		return p.arg.Name() + " " + types.TypeString(p.arg.Type(), qf)
+	case ValueProvider:
+		if p.iface != nil {
+			return fmt.Sprintf("wire.InterfaceValue(new(%s))", types.TypeString(p.iface, qf))
+		}
+		return fmt.Sprintf("wire.Value(%s)", types.TypeString(p.vtype, qf))
+	}
+	name := p.fn.Name() + typeArgsString(p.targs, qf)
+	if qf != nil {
+		if q := qf(p.fn.Pkg()); q != "" {
+			return q + "." + name
+		}
+	}
+	return name
+}
+
+// FullName is the name qualified by package path, e.g. github.com/google/wire.NewSet,
+// for other kinds it is the same as String
+func (p *Provider) FullName() string {
+	if p.kind != FuncProvider {
+		return p.String()
+	}
+	return p.Package() + "." + p.Name() + typeArgsString(p.targs, nil)
+}
+
+// typeArgsString format targs as [T1, T2] qualified by qf, empty if there is no type argument
+func typeArgsString(targs []types.Type, qf types.Qualifier) string {
+	if len(targs) == 0 {
+		return ""
+	}
+	list := make([]string, 0, len(targs))
+	for _, t := range targs {
+		list = append(list, types.TypeString(t, qf))
+	}
+	return "[" + strings.Join(list, ", ") + "]"
+}
+
+func (p *Provider) Pos() token.Pos {
+	if p.kind != FuncProvider {
+		return p.pos
+	}
+	return p.fn.Pos()
+}
+
 func (p *Provider) Package() string {
+	if p.kind != FuncProvider {
+		return p.pkg
+	}
 	return p.fn.Pkg().Path()
 }
 
 // getBean convert param or result to Bean
 func (p *Provider) fromVar(v *types.Var) *Bean {
-	origin := v.Origin().Type()
+	// the type of v itself rather than its origin, so instances of a generic type are distinct beans
+	return p.beanOf(v.Type())
+}
+
+func (p *Provider) beanOf(origin types.Type) *Bean {
 	switch typ := origin.(type) {
 	case *types.Named:
 		// named interface or struct or pointer
@@ -58,17 +368,18 @@ func (p *Provider) fromVar(v *types.Var) *Bean {
 		return bean
 	case *types.Interface:
 		// same as caller package
-		bean := &Bean{pkg: p.fn.Pkg().Path(), typ: typ}
+		bean := &Bean{pkg: p.Package(), typ: typ}
 		return bean
 	case *types.Pointer:
-		bean := &Bean{pkg: deref(p.fn.Pkg().Path(), typ), typ: typ}
+		bean := &Bean{pkg: deref(p.Package(), typ), typ: typ}
 		return bean
 	case *types.Struct:
-		bean := &Bean{pkg: p.fn.Pkg().Path(), typ: typ}
+		bean := &Bean{pkg: p.Package(), typ: typ}
 		return bean
 	default:
-		slog.Warn("something should not happen!", "type", typ)
-		panic(typ)
+		// basic or composite types, same as caller package
+		bean := &Bean{pkg: p.Package(), typ: typ}
+		return bean
 	}
 }
 
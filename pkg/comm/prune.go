@@ -0,0 +1,76 @@
+package comm
+
+import (
+	"slices"
+
+	"github.com/dave/dst"
+)
+
+// buildArg is an argument of the wire.Build call and the providers it contains,
+// fn report whether it is a provider function rather than a set, bind, struct or value
+type buildArg struct {
+	expr      dst.Expr
+	providers []*Provider
+	fn        bool
+}
+
+// AddBuildArg record that the argument expr of wire.Build contains providers, so that it can be pruned
+func (inj *Injector) AddBuildArg(expr dst.Expr, providers []*Provider, fn bool) {
+	inj.args = append(inj.args, &buildArg{expr: expr, providers: providers, fn: fn})
+}
+
+// Provider find the provider of bean in the injector, bean without qualifier can be provided by any provider of its type
+func (inj *Injector) Provider(bean *Bean) *Provider {
+	var found *Provider
+	for _, p := range inj.Providers() {
+		for _, b := range p.ProvideAll() {
+			if b.Id() == bean.Id() {
+				return p
+			}
+			if found == nil && bean.qualifier == "" && b.Identical(bean) {
+				found = p
+			}
+		}
+	}
+	return found
+}
+
+// Reachable report providers the output of the injector depends on directly or indirectly
+func (inj *Injector) Reachable() map[*Provider]bool {
+	reachable := map[*Provider]bool{}
+	queue := []*Bean{inj.Output()}
+	for len(queue) > 0 {
+		bean := queue[0]
+		queue = queue[1:]
+		p := inj.Provider(bean)
+		if p == nil || reachable[p] {
+			continue
+		}
+		reachable[p] = true
+		queue = append(queue, p.Require()...)
+	}
+	return reachable
+}
+
+// Prune remove the arguments of wire.Build whose providers are all unreachable from the output,
+// only provider functions are removed unless all is set, return the providers removed
+func (inj *Injector) Prune(all bool) []*Provider {
+	reachable := inj.Reachable()
+	var pruned []*Provider
+	for _, arg := range inj.args {
+		if !arg.fn && !all {
+			continue
+		}
+		if slices.ContainsFunc(arg.providers, func(p *Provider) bool { return reachable[p] }) {
+			continue
+		}
+		inj.buildCall.Args = slices.DeleteFunc(inj.buildCall.Args, func(e dst.Expr) bool { return e == arg.expr })
+		for _, p := range arg.providers {
+			delete(inj.origin, p.String())
+			delete(inj.providers, p.String())
+		}
+		pruned = append(pruned, arg.providers...)
+		inj.pruned = true
+	}
+	return pruned
+}
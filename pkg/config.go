@@ -1,12 +1,15 @@
 package pkg
 
 import (
+	"cmp"
 	"fmt"
 	"go/types"
 	"slices"
 	"strings"
 
+	"github.com/hauntedness/autowire/conf"
 	"github.com/hauntedness/autowire/pkg/comm"
+	"golang.org/x/tools/go/packages"
 )
 
 // optional config when processing autowire
@@ -22,12 +25,67 @@ type ProcessConfigurer interface {
 	// used to report whether a function can be provider
 	ProviderPredicate(fn *types.Func) bool
 
-	// used to find proper provider from multiple ones
-	ProviderElect(inj *comm.Injector, bean *comm.Bean, providers map[string]*comm.Provider) *comm.Provider
+	// used to report whether providers declared in the package can be used to complete injectors
+	PackagePredicate(pkg *packages.Package) bool
+
+	// used to report whether a struct type without provider function can be provided by wire.Struct(new(T), "*")
+	StructPredicate(obj *types.TypeName) bool
+
+	// used to find proper provider from the candidates of the election, report error if none is proper,
+	// candidates not elected can be recorded with the reason by Election.Eliminate
+	ProviderElect(e *Election) (*comm.Provider, error)
+
+	// used to report which arguments of wire.Build not needed by the injector output are removed
+	PruneMode() PruneMode
+
+	// used to report whether to sort all arguments of wire.Build, added providers are always sorted
+	WillSortArgs() bool
 }
 
+// PruneMode decide which arguments of wire.Build are removed when no provider in it is reachable from the injector output
+type PruneMode int
+
+const (
+	PruneNone  PruneMode = iota // keep all arguments
+	PruneFuncs                  // remove provider functions only, sets, binds, structs and values are kept
+	PruneAll                    // remove any argument, including sets, binds, structs and values
+)
+
 type DefaultProcessConfigurer struct {
-	willRewriteSource bool
+	// whether to save the refactored source code, if false, autowire runs as dry run
+	RewriteSource bool
+	// whether to provide struct types without provider function by wire.Struct
+	StructProvider bool
+	// whether to fail with AmbiguousProviderError rather than elect one when a bean has multiple providers and none is primary
+	Strict bool
+	// name patterns of provider functions, default to New*, see conf.Match
+	ProviderPatterns []string
+	// package path patterns where providers are searched or never searched
+	Include []string
+	Exclude []string
+	// whether to search providers only in the main module, or never in the standard library
+	ModuleOnly bool
+	ExcludeStd bool
+	// preferred provider by type, the key is the full type, e.g. *database/sql.DB,
+	// the value is the full name of the provider, e.g. github.com/x/db.NewPostgres
+	Prefer map[string]string
+	// which unused arguments of wire.Build are removed
+	Prune PruneMode
+	// whether to sort all arguments of wire.Build in topological order, not only the added ones
+	Sort bool
+}
+
+// NewDefaultProcessConfigurer create DefaultProcessConfigurer from the project level configuration
+func NewDefaultProcessConfigurer(c *conf.Config) *DefaultProcessConfigurer {
+	return &DefaultProcessConfigurer{
+		RewriteSource:    c.Rewrite(),
+		ProviderPatterns: c.Providers,
+		Include:          c.Include,
+		Exclude:          c.Exclude,
+		ModuleOnly:       c.ModuleOnly,
+		ExcludeStd:       c.ExcludeStd,
+		Prefer:           c.Prefer,
+	}
 }
 
 // InjectorPredicate implements ProcessConfigurer
@@ -35,57 +93,131 @@ func (*DefaultProcessConfigurer) InjectorPredicate(fn *types.Func) bool {
 	return true
 }
 
-// ProviderElector implements ProcessConfigurer
-func (*DefaultProcessConfigurer) ProviderElect(inj *comm.Injector, bean *comm.Bean, providers map[string]*comm.Provider) *comm.Provider {
-	firstSequence := make([]*comm.Provider, 0, 1)
-	secondSequence := make([]*comm.Provider, 0, 1)
-	for _, p := range providers {
-		if p.Package() == inj.Package() {
-			firstSequence = append(firstSequence, p)
-		} else if len(firstSequence) == 0 {
-			secondSequence = append(secondSequence, p)
+// ProviderElect implements ProcessConfigurer,
+// providers marked by //autowire:primary win, then the one preferred by Prefer, then the ones declared in the injector package,
+// then the ones requiring more beans, then by name,
+// in strict mode, multiple candidates not decided by //autowire:primary or Prefer are reported as AmbiguousProviderError instead
+func (c *DefaultProcessConfigurer) ProviderElect(e *Election) (*comm.Provider, error) {
+	if len(e.Candidates) == 0 {
+		return nil, &ProviderNotFoundError{Injector: e.Injector.String(), Bean: e.Bean.Id()}
+	}
+	list := explicitChoice(e, e.Sorted(), c.Prefer)
+	if c.Strict && len(list) > 1 {
+		candidates := make([]string, 0, len(list))
+		for _, p := range list {
+			candidates = append(candidates, fmt.Sprintf("%s (%s)", p.FullName(), e.Position(p)))
+		}
+		return nil, &AmbiguousProviderError{Injector: e.Injector.String(), Bean: e.Bean.Id(), Candidates: candidates}
+	}
+	qf := relativeTo(e.Injector.Package())
+	local := slices.DeleteFunc(slices.Clone(list), func(p *comm.Provider) bool {
+		return p.Package() != e.Injector.Package()
+	})
+	if len(local) > 0 {
+		for _, p := range list {
+			if p.Package() != e.Injector.Package() {
+				e.Eliminate(p, "not declared in the injector package")
+			}
 		}
+		list = local
 	}
-	sortFunc := func(prev, next *comm.Provider) int {
-		if len(prev.Require()) > len(next.Require()) {
-			return -1
+	slices.SortStableFunc(list, func(a, b *comm.Provider) int {
+		if n := cmp.Compare(len(b.Require()), len(a.Require())); n != 0 {
+			return n
 		}
-		if prev.Name() < next.Name() {
-			return -1
+		return cmp.Compare(a.Name(), b.Name())
+	})
+	winner := list[0]
+	for _, p := range list[1:] {
+		if len(p.Require()) < len(winner.Require()) {
+			e.Eliminate(p, fmt.Sprintf("requires %d beans, fewer than %s", len(p.Require()), winner.Label(qf)))
+		} else {
+			e.Eliminate(p, "name sorts after "+winner.Label(qf))
 		}
-		return 1
 	}
-	if len(firstSequence) > 0 {
-		slices.SortFunc(firstSequence, sortFunc)
-		return firstSequence[0]
+	return winner, nil
+}
+
+// explicitChoice narrow list to the providers marked by //autowire:primary, then to the one preferred by prefer,
+// list is returned as it is if there is no such choice, the providers left out are eliminated from e
+func explicitChoice(e *Election, list []*comm.Provider, prefer map[string]string) []*comm.Provider {
+	primary := slices.DeleteFunc(slices.Clone(list), func(p *comm.Provider) bool {
+		return !p.Primary()
+	})
+	if len(primary) > 0 {
+		for _, p := range list {
+			if !p.Primary() {
+				e.Eliminate(p, "not marked by "+primaryDirective)
+			}
+		}
+		list = primary
 	}
-	if len(secondSequence) > 0 {
-		slices.SortFunc(secondSequence, sortFunc)
-		return secondSequence[0]
+	if name, ok := prefer[e.Bean.String()]; ok && len(list) > 1 {
+		preferred := slices.DeleteFunc(slices.Clone(list), func(p *comm.Provider) bool {
+			return p.FullName() != name
+		})
+		if len(preferred) > 0 {
+			for _, p := range list {
+				if p.FullName() != name {
+					e.Eliminate(p, "not the preferred provider "+name)
+				}
+			}
+			list = preferred
+		}
 	}
-	panic(fmt.Errorf("no provider given, bean: %v", bean.String())) // not reachable code or this will be treated as a bug
+	return list
 }
 
 // ProviderPredicate implements ProcessConfigurer
-func (*DefaultProcessConfigurer) ProviderPredicate(fn *types.Func) bool {
-	sig, ok := fn.Type().(*types.Signature)
-	if !ok {
+func (c *DefaultProcessConfigurer) ProviderPredicate(fn *types.Func) bool {
+	// params of basic or composite types are accepted, they are checked against the injector when elected
+	if _, ok := fn.Type().(*types.Signature); !ok {
 		return false
 	}
-	params := sig.Params()
-	for i := 0; i < params.Len(); i++ {
-		param := params.At(i)
-		typ := param.Origin().Type()
-		if !satisfyBeanDefinition(typ) {
-			return false
-		}
+	if len(c.ProviderPatterns) > 0 {
+		return conf.Match(c.ProviderPatterns, fn.Name())
 	}
 	return strings.HasPrefix(fn.Name(), "New")
 }
 
+// PackagePredicate implements ProcessConfigurer
+func (c *DefaultProcessConfigurer) PackagePredicate(pkg *packages.Package) bool {
+	if c.ModuleOnly && (pkg.Module == nil || !pkg.Module.Main) {
+		return false
+	}
+	if c.ExcludeStd && isStd(pkg) {
+		return false
+	}
+	if len(c.Include) > 0 && !conf.Match(c.Include, pkg.PkgPath) {
+		return false
+	}
+	return !conf.Match(c.Exclude, pkg.PkgPath)
+}
+
+// isStd report whether pkg is in the standard library, whose path has no dot in the first element
+func isStd(pkg *packages.Package) bool {
+	first, _, _ := strings.Cut(pkg.PkgPath, "/")
+	return pkg.Module == nil && !strings.Contains(first, ".")
+}
+
+// PruneMode implements ProcessConfigurer
+func (c *DefaultProcessConfigurer) PruneMode() PruneMode {
+	return c.Prune
+}
+
+// WillSortArgs implements ProcessConfigurer
+func (c *DefaultProcessConfigurer) WillSortArgs() bool {
+	return c.Sort
+}
+
+// StructPredicate implements ProcessConfigurer
+func (c *DefaultProcessConfigurer) StructPredicate(obj *types.TypeName) bool {
+	return c.StructProvider
+}
+
 // WillRewriteSource implements ProcessConfigurer
 func (c *DefaultProcessConfigurer) WillRewriteSource() bool {
-	return c.willRewriteSource
+	return c.RewriteSource
 }
 
 var _ ProcessConfigurer = (*DefaultProcessConfigurer)(nil)
@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/dave/dst/decorator/resolver/gopackages"
+	"github.com/hauntedness/autowire/pkg/util"
+)
+
+// Diff write the changes of refactored wireinject files as unified diff against the files on disk,
+// it should be called after Process, with WillRewriteSource reporting false so that nothing is saved
+func (di *DIContext) Diff(w io.Writer) error {
+	refs := di.sortedFiles()
+	wd, _ := os.Getwd()
+	for _, ref := range refs {
+		pkg := di.pkgs[ref.importPath]
+		origin, err := os.ReadFile(ref.name)
+		if err != nil {
+			return err
+		}
+		current, err := renderFile(pkg, di.files[ref].File())
+		if err != nil {
+			return err
+		}
+		current = matchLineEndings(origin, current)
+		name := ref.name
+		if rel, err := filepath.Rel(wd, name); err == nil {
+			name = rel
+		}
+		name = filepath.ToSlash(name)
+		if _, err := io.WriteString(w, util.UnifiedDiff("a/"+name, "b/"+name, string(origin), string(current))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderFile print file the same way as decorator.Package.Save does
+func renderFile(pkg *decorator.Package, file *dst.File) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	restorer := decorator.NewRestorerWithImports(pkg.PkgPath, gopackages.New(pkg.Dir))
+	if err := restorer.Fprint(buf, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// savePackage write the files of pkg as decorator.Package.Save does, but keep the line endings of the files on disk
+func savePackage(pkg *decorator.Package) error {
+	for _, file := range pkg.Syntax {
+		name := pkg.Decorator.Filenames[file]
+		src, err := renderFile(pkg, file)
+		if err != nil {
+			return err
+		}
+		if origin, err := os.ReadFile(name); err == nil {
+			src = matchLineEndings(origin, src)
+		}
+		if err := os.WriteFile(name, src, 0o666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchLineEndings convert the line endings of src to CRLF if origin uses CRLF, as the printer always writes LF
+func matchLineEndings(origin, src []byte) []byte {
+	if !bytes.Contains(origin, []byte("\r\n")) {
+		return src
+	}
+	src = bytes.ReplaceAll(src, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(src, []byte("\n"), []byte("\r\n"))
+}
@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hauntedness/autowire/pkg/util"
+)
+
+func TestDIContext_Diff(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/incomplete"); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := di.Diff(&sb); err != nil {
+		t.Fatal(err)
+	}
+	diff := sb.String()
+	for _, want := range []string{
+		"--- a/",
+		"+++ b/",
+		`+	"github.com/hauntedness/autowire/example/inj/guan"`,
+		"-	wire.Build(liu.NewLiu)",
+		"+	wire.Build(liu.NewLiu, ",
+	} {
+		if !strings.Contains(diff, want) {
+			t.Errorf("expecting diff contains %q, got:\n%s", want, diff)
+		}
+	}
+}
+
+// test CRLF files only show the changed lines, and a missing newline at end of file is marked
+func TestUnifiedDiff(t *testing.T) {
+	origin := "a\r\nb\r\nc\r\n"
+	current := string(matchLineEndings([]byte(origin), []byte("a\nB\nc\n")))
+	want := "--- a/x.go\n+++ b/x.go\n@@ -1,3 +1,3 @@\n a\r\n-b\r\n+B\r\n c\r\n"
+	if got := util.UnifiedDiff("a/x.go", "b/x.go", origin, current); got != want {
+		t.Errorf("unexpected diff of CRLF text:\n%q\nwant:\n%q", got, want)
+	}
+	want = "--- a/x.go\n+++ b/x.go\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n"
+	if got := util.UnifiedDiff("a/x.go", "b/x.go", "a\nb", "a\nb\n"); got != want {
+		t.Errorf("unexpected diff of text without newline at end:\n%q\nwant:\n%q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/types"
+	"strings"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/hauntedness/autowire/pkg/comm"
+)
+
+// directives written in the doc comment of functions
+const (
+	providerDirective = "//autowire:provider" // take the function as provider even if ProviderPredicate reject it
+	primaryDirective  = "//autowire:primary"  // the provider wins the election of its output, implies provider
+	ignoreDirective   = "//autowire:ignore"   // never take the function as provider
+	// //autowire:qualifier name qualify the output of the provider,
+	// //autowire:qualifier param=name qualify the bean required by the parameter
+	qualifierDirective = "//autowire:qualifier"
+)
+
+type directives struct {
+	provider  bool
+	primary   bool
+	ignore    bool
+	qualifier string
+	params    map[string]string // qualifiers of parameters by name
+}
+
+func (dirs *directives) empty() bool {
+	return !dirs.provider && !dirs.primary && !dirs.ignore && dirs.qualifier == "" && len(dirs.params) == 0
+}
+
+// newFuncProvider create provider of fn with its directives applied
+func newFuncProvider(fn *types.Func, dirs directives) *comm.Provider {
+	return applyDirectives(comm.NewProvider(fn), dirs)
+}
+
+// applyDirectives set the primary flag and qualifiers of p by dirs
+func applyDirectives(p *comm.Provider, dirs directives) *comm.Provider {
+	p.SetPrimary(dirs.primary)
+	p.SetQualifier(dirs.qualifier)
+	for param, qualifier := range dirs.params {
+		p.SetParamQualifier(param, qualifier)
+	}
+	return p
+}
+
+// funcDirectives collect directives of package level functions in pkg from the decorations of dst
+func funcDirectives(pkg *decorator.Package) map[*types.Func]directives {
+	ret := map[*types.Func]directives{}
+	if pkg.Decorator == nil {
+		return ret
+	}
+	for _, file := range pkg.Syntax {
+		for _, decl := range file.Decls {
+			fd, ok := decl.(*dst.FuncDecl)
+			if !ok || fd.Recv != nil {
+				continue
+			}
+			var dirs directives
+			for _, c := range fd.Decs.Start {
+				name, args := parseDirective(c)
+				switch name {
+				case providerDirective:
+					dirs.provider = true
+				case primaryDirective:
+					dirs.primary = true
+				case ignoreDirective:
+					dirs.ignore = true
+				case qualifierDirective:
+					for _, arg := range args {
+						param, qualifier, ok := strings.Cut(arg, "=")
+						if !ok {
+							dirs.qualifier = arg
+							continue
+						}
+						if dirs.params == nil {
+							dirs.params = map[string]string{}
+						}
+						dirs.params[param] = qualifier
+					}
+				}
+			}
+			if dirs.empty() {
+				continue
+			}
+			astDecl, ok := pkg.Decorator.Ast.Nodes[fd].(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			if fn, ok := pkg.TypesInfo.Defs[astDecl.Name].(*types.Func); ok {
+				ret[fn] = dirs
+			}
+		}
+	}
+	return ret
+}
+
+// parseDirective return the directive of comment and its arguments, e.g. //autowire:qualifier and [db=primary],
+// name is empty if it is not a directive
+func parseDirective(comment string) (name string, args []string) {
+	if !strings.HasPrefix(comment, "//autowire:") {
+		return "", nil
+	}
+	fields := strings.Fields(comment)
+	return fields[0], fields[1:]
+}
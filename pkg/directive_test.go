@@ -0,0 +1,45 @@
+package pkg
+
+import (
+	"slices"
+	"testing"
+)
+
+// test providers are discovered by //autowire:provider, //autowire:primary and //autowire:ignore
+func TestDIContext_ProcessDirectives(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{Strict: true}, nil)
+	path := "github.com/hauntedness/autowire/example/directive"
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
+	if !di.ignored[objRef{importPath: path + "/store", name: "NewLegacyCache"}] {
+		t.Errorf("expecting NewLegacyCache ignored")
+	}
+	injector := di.injectors[objRef{importPath: path, name: "InitApp"}]
+	assertNotNil(t, injector)
+	var added []string
+	for _, p := range injector.Added() {
+		added = append(added, p.Name())
+	}
+	if !slices.Equal(added, []string{"NewPostgresStore", "OpenCache"}) {
+		t.Fatalf("expecting NewPostgresStore and OpenCache added, got %v", added)
+	}
+}
+
+// test provider is chosen by the qualifier required by the parameter
+func TestDIContext_ProcessQualifier(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{Strict: true}, nil)
+	path := "github.com/hauntedness/autowire/example/qualifier"
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
+	injector := di.injectors[objRef{importPath: path, name: "InitRepo"}]
+	assertNotNil(t, injector)
+	var added []string
+	for _, p := range injector.Added() {
+		added = append(added, p.Name())
+	}
+	if !slices.Equal(added, []string{"NewReplicaDB"}) {
+		t.Fatalf("expecting only NewReplicaDB added, got %v", added)
+	}
+}
@@ -0,0 +1,249 @@
+package pkg
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Error is a failure found while processing, Pos is the position of the offending injector or provider
+type Error struct {
+	Pos token.Position
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Pos.IsValid() {
+		return e.Pos.String() + ": " + e.Err.Error()
+	}
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorList is the error returned by DIContext.Process, each element is reported as file:line:col: message
+type ErrorList []*Error
+
+func (list ErrorList) Error() string {
+	lines := make([]string, 0, len(list))
+	for _, e := range list {
+		lines = append(lines, e.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (list ErrorList) Unwrap() []error {
+	errs := make([]error, 0, len(list))
+	for _, e := range list {
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// Sort sort the list by file, line, column and message
+func (list ErrorList) Sort() {
+	slices.SortStableFunc(list, func(a, b *Error) int {
+		return cmp.Or(
+			cmp.Compare(a.Pos.Filename, b.Pos.Filename),
+			cmp.Compare(a.Pos.Line, b.Pos.Line),
+			cmp.Compare(a.Pos.Column, b.Pos.Column),
+			cmp.Compare(a.Err.Error(), b.Err.Error()),
+		)
+	})
+}
+
+// Err return nil if list is empty, otherwise list itself
+func (list ErrorList) Err() error {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// LoadError is reported when a package can not be loaded or type checked
+type LoadError struct {
+	Pattern string
+	Msg     string
+}
+
+func (e *LoadError) Error() string {
+	if e.Pattern == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("load %s: %s", e.Pattern, e.Msg)
+}
+
+// InjectorError is reported when an injector function is malformed, e.g. the body of wire.Build is invalid
+type InjectorError struct {
+	Injector string
+	Err      error
+}
+
+func (e *InjectorError) Error() string {
+	return fmt.Sprintf("injector %s: %v", e.Injector, e.Err)
+}
+
+func (e *InjectorError) Unwrap() error {
+	return e.Err
+}
+
+// ProviderNotFoundError is reported when no provider can be found for a bean required by an injector
+type ProviderNotFoundError struct {
+	Injector string
+	Bean     string
+}
+
+func (e *ProviderNotFoundError) Error() string {
+	return fmt.Sprintf("injector %s: no provider found for %s", e.Injector, e.Bean)
+}
+
+// AmbiguousBindingError is reported when more than one implementation can be bound to an interface
+type AmbiguousBindingError struct {
+	Injector   string
+	Bean       string
+	Candidates []string
+}
+
+func (e *AmbiguousBindingError) Error() string {
+	return fmt.Sprintf("injector %s: ambiguous implementations for %s, add wire.Bind for one of: %s", e.Injector, e.Bean, strings.Join(e.Candidates, ", "))
+}
+
+// AmbiguousProviderError is reported in strict mode when more than one provider can provide a bean,
+// candidates are in form of name (file:line:col)
+type AmbiguousProviderError struct {
+	Injector   string
+	Bean       string
+	Candidates []string
+}
+
+func (e *AmbiguousProviderError) Error() string {
+	return fmt.Sprintf("injector %s: ambiguous providers for %s, add one of them to wire.Build: %s", e.Injector, e.Bean, strings.Join(e.Candidates, ", "))
+}
+
+// IncompatibleProviderError is reported when providers of a bean all return error or cleanup function
+// which the injector signature can not propagate, candidates are in form of name (reason)
+type IncompatibleProviderError struct {
+	Injector   string
+	Bean       string
+	Candidates []string
+}
+
+func (e *IncompatibleProviderError) Error() string {
+	return fmt.Sprintf("injector %s: no compatible provider for %s, change the injector results or add a provider: %s", e.Injector, e.Bean, strings.Join(e.Candidates, ", "))
+}
+
+// UnresolvablePrimitiveError is reported when a basic or composite type without name, e.g. string or []byte,
+// is neither an argument of the injector nor given by wire.Value, candidates are the providers of the bean
+// eliminated for requiring it, in form of name (type)
+type UnresolvablePrimitiveError struct {
+	Injector   string
+	Bean       string
+	Candidates []string
+}
+
+func (e *UnresolvablePrimitiveError) Error() string {
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("injector %s: unresolvable primitive dependency %s, pass it as an argument of the injector or by wire.Value", e.Injector, e.Bean)
+	}
+	return fmt.Sprintf("injector %s: no provider for %s, unresolvable primitive dependency of: %s", e.Injector, e.Bean, strings.Join(e.Candidates, ", "))
+}
+
+// NoAllowedProviderError is reported when providers of a bean are all declared in packages not allowed by the configurer
+type NoAllowedProviderError struct {
+	Injector string
+	Bean     string
+	Rejected []string
+}
+
+func (e *NoAllowedProviderError) Error() string {
+	return fmt.Sprintf("injector %s: no allowed provider for %s, providers in packages not allowed: %s", e.Injector, e.Bean, strings.Join(e.Rejected, ", "))
+}
+
+// QualifierConflictError is reported when an injector requires a qualified bean while its type is provided by another provider,
+// as wire allows only one provider for a type
+type QualifierConflictError struct {
+	Injector string
+	Bean     string
+	Provided string
+}
+
+func (e *QualifierConflictError) Error() string {
+	return fmt.Sprintf("injector %s: require %s, but the type is already provided as %s", e.Injector, e.Bean, e.Provided)
+}
+
+// GenerateError is reported when wire fails to generate the package
+type GenerateError struct {
+	Package string
+	Err     error
+}
+
+func (e *GenerateError) Error() string {
+	return fmt.Sprintf("generate %s: %v", e.Package, e.Err)
+}
+
+func (e *GenerateError) Unwrap() error {
+	return e.Err
+}
+
+// IncompleteInjectorError is reported in check mode when autowire would add providers to the injector
+type IncompleteInjectorError struct {
+	Injector  string
+	Providers []string
+}
+
+func (e *IncompleteInjectorError) Error() string {
+	return fmt.Sprintf("injector %s is incomplete, missing providers: %s", e.Injector, strings.Join(e.Providers, ", "))
+}
+
+// report append err to the error list of the context
+func (di *DIContext) report(pos token.Position, err error) {
+	di.errs = append(di.errs, &Error{Pos: pos, Err: err})
+}
+
+// position convert pos to token.Position with the file set shared by all loaded packages
+func (di *DIContext) position(pos token.Pos) token.Position {
+	return di.loadConf.Fset.Position(pos)
+}
+
+// reportPackageErrors report errors of pkg, and return false if there is any
+func (di *DIContext) reportPackageErrors(pattern string, pkg *packages.Package) bool {
+	for _, e := range pkg.Errors {
+		di.report(parsePosition(e.Pos), &LoadError{Pattern: pattern, Msg: e.Msg})
+	}
+	return len(pkg.Errors) == 0
+}
+
+// parsePosition parse position in form of file:line:col which is used by packages.Error
+func parsePosition(pos string) token.Position {
+	if pos == "" || pos == "-" {
+		return token.Position{}
+	}
+	position := token.Position{Filename: pos}
+	parts := strings.Split(pos, ":")
+	// try col and line from the end, as file name may contain colon
+	nums := make([]int, 0, 2)
+	for i := len(parts) - 1; i > 0 && len(nums) < 2; i-- {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			break
+		}
+		nums = append(nums, n)
+	}
+	switch len(nums) {
+	case 1:
+		position.Filename = strings.Join(parts[:len(parts)-1], ":")
+		position.Line = nums[0]
+	case 2:
+		position.Filename = strings.Join(parts[:len(parts)-2], ":")
+		position.Line = nums[1]
+		position.Column = nums[0]
+	}
+	return position
+}
@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"go/types"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/hauntedness/autowire/pkg/comm"
+)
+
+// Election record how the provider of a bean is decided when resolving an injector
+type Election struct {
+	Injector   *comm.Injector
+	Bean       *comm.Bean
+	Candidates map[string]*comm.Provider // providers whose output is the bean
+	Eliminated []*Elimination            // candidates not elected, in the order of elimination
+	Winner     *comm.Provider            // nil if no provider is found
+	Note       string                    // how the winner is decided without election, or why it fails
+	fset       *token.FileSet
+}
+
+// Elimination is a candidate not elected and the reason
+type Elimination struct {
+	Provider *comm.Provider
+	Reason   string
+}
+
+// Eliminate record that p is not elected for reason
+func (e *Election) Eliminate(p *comm.Provider, reason string) {
+	e.Eliminated = append(e.Eliminated, &Elimination{Provider: p, Reason: reason})
+}
+
+// Position report the position where p is declared
+func (e *Election) Position(p *comm.Provider) token.Position {
+	if e.fset == nil {
+		return token.Position{}
+	}
+	return e.fset.Position(p.Pos())
+}
+
+// Sorted report the candidates sorted by String
+func (e *Election) Sorted() []*comm.Provider {
+	list := make([]*comm.Provider, 0, len(e.Candidates))
+	for _, p := range e.Candidates {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b *comm.Provider) int {
+		return cmp.Compare(a.String(), b.String())
+	})
+	return list
+}
+
+// Match report whether typ names the bean, it can be the full type, e.g. *github.com/x/y.T,
+// or qualified by package name, e.g. *y.T, or unqualified if the bean is in the injector package
+func (e *Election) Match(typ string) bool {
+	t := e.Bean.Type()
+	return typ == e.Bean.String() ||
+		typ == types.TypeString(t, (*types.Package).Name) ||
+		typ == types.TypeString(t, relativeTo(e.Injector.Package()))
+}
+
+// Elections report the decisions made when resolving injectors, sorted by injector and bean,
+// only the ones of the bean are reported if typ is not empty, see Election.Match
+func (di *DIContext) Elections(typ string) []*Election {
+	var list []*Election
+	for _, e := range di.elections {
+		if typ == "" || e.Match(typ) {
+			list = append(list, e)
+		}
+	}
+	slices.SortStableFunc(list, func(a, b *Election) int {
+		if n := cmp.Compare(a.Injector.String(), b.Injector.String()); n != 0 {
+			return n
+		}
+		return cmp.Compare(a.Bean.Id(), b.Bean.Id())
+	})
+	return list
+}
+
+// WriteElections write the decision trace of elections to w
+func WriteElections(w io.Writer, elections []*Election) error {
+	b := &strings.Builder{}
+	for _, e := range elections {
+		qf := relativeTo(e.Injector.Package())
+		fmt.Fprintf(b, "%s.%s needs %s\n", e.Injector.Package(), e.Injector.Name(), beanLabel(e.Bean, qf))
+		for _, el := range e.Eliminated {
+			fmt.Fprintf(b, "\teliminated %s: %s\n", el.Provider.Label(qf), el.Reason)
+		}
+		switch {
+		case e.Winner == nil:
+			fmt.Fprintf(b, "\tfailed: %s\n", e.Note)
+		case e.Note != "":
+			fmt.Fprintf(b, "\telected %s: %s\n", e.Winner.Label(qf), e.Note)
+		case len(e.Candidates) == 1:
+			fmt.Fprintf(b, "\telected %s: the only candidate\n", e.Winner.Label(qf))
+		default:
+			fmt.Fprintf(b, "\telected %s\n", e.Winner.Label(qf))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
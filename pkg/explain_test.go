@@ -0,0 +1,93 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDIContext_Elections(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Resolve("github.com/hauntedness/autowire/example/elect"); err != nil {
+		t.Fatal(err)
+	}
+	elections := di.Elections("*store.Store")
+	if len(elections) != 1 {
+		t.Fatalf("expecting 1 election, got %d", len(elections))
+	}
+	e := elections[0]
+	if e.Winner == nil || e.Winner.Name() != "NewCachedStore" {
+		t.Fatalf("expecting NewCachedStore elected, got %v", e.Winner)
+	}
+	if len(e.Eliminated) != 1 || e.Eliminated[0].Provider.Name() != "NewStore" {
+		t.Fatalf("expecting NewStore eliminated, got %v", e.Eliminated)
+	}
+	b := &strings.Builder{}
+	if err := WriteElections(b, elections); err != nil {
+		t.Fatal(err)
+	}
+	want := "\teliminated store.NewStore: requires 0 beans, fewer than store.NewCachedStore\n"
+	if !strings.Contains(b.String(), want) {
+		t.Errorf("expecting %q in trace:\n%s", want, b)
+	}
+}
+
+func TestDIContext_Strict(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{Strict: true}, nil)
+	err := di.Resolve("github.com/hauntedness/autowire/example/elect")
+	var ambiguous *AmbiguousProviderError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expecting AmbiguousProviderError, got %v", err)
+	}
+	if len(ambiguous.Candidates) != 2 || !strings.Contains(ambiguous.Candidates[0], "store.go:") {
+		t.Errorf("expecting 2 candidates with positions, got %v", ambiguous.Candidates)
+	}
+}
+
+func TestDIContext_Prefer(t *testing.T) {
+	store := "github.com/hauntedness/autowire/example/elect/store"
+	di := NewDIContext(&DefaultProcessConfigurer{
+		Strict: true,
+		Prefer: map[string]string{"*" + store + ".Store": store + ".NewStore"},
+	}, nil)
+	if err := di.Resolve("github.com/hauntedness/autowire/example/elect"); err != nil {
+		t.Fatal(err)
+	}
+	e := di.Elections("*store.Store")[0]
+	if e.Winner == nil || e.Winner.Name() != "NewStore" {
+		t.Fatalf("expecting preferred NewStore elected, got %v", e.Winner)
+	}
+}
+
+// test providers returning error are eliminated for injectors not returning error
+func TestDIContext_ElectionsIncompatible(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Resolve("github.com/hauntedness/autowire/example/resource"); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range di.Elections("*db.DB") {
+		want := "NewDB"
+		if e.Injector.Name() == "InitRepo" {
+			want = "NewMemDB"
+		}
+		if e.Winner == nil || e.Winner.Name() != want {
+			t.Errorf("expecting %s elected for %s, got %v", want, e.Injector.Name(), e.Winner)
+		}
+	}
+	di = NewDIContext(&DefaultProcessConfigurer{}, nil)
+	err := di.Resolve("github.com/hauntedness/autowire/example/incompatible")
+	var incompatible *IncompatibleProviderError
+	if !errors.As(err, &incompatible) || len(incompatible.Candidates) != 1 {
+		t.Fatalf("expecting IncompatibleProviderError, got %v", err)
+	}
+}
+
+// test the struct fallback does not hide a provider returning error the injector can not return
+func TestDIContext_IncompatibleStruct(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{StructProvider: true}, nil)
+	err := di.Resolve("github.com/hauntedness/autowire/example/incompatible")
+	var incompatible *IncompatibleProviderError
+	if !errors.As(err, &incompatible) || len(incompatible.Candidates) != 1 {
+		t.Fatalf("expecting IncompatibleProviderError, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dave/dst/decorator"
+	"github.com/hauntedness/autowire/pkg/wiregen"
+	"golang.org/x/tools/go/packages"
+)
+
+// Generate write wire_gen.go for the packages containing injectors with the generator of wire,
+// it should be called after Process, the loaded packages are reused so nothing is loaded again.
+// tags are the additional build tags written in the generated files.
+// Errors of wire are reported as GenerateError with their positions, files are not written in dry run.
+// Packages with injectors failing to resolve are skipped, as wire would fail on them too.
+func (di *DIContext) Generate(tags string) error {
+	for _, result := range di.generate(tags) {
+		if len(result.Errs) > 0 {
+			continue
+		}
+		if !di.conf.WillRewriteSource() {
+			slog.Info("dry run, skip writing generated file", "path", result.OutputPath)
+			continue
+		}
+		slog.Info("writing generated file", "path", result.OutputPath)
+		if err := result.Commit(); err != nil {
+			di.report(token.Position{}, &GenerateError{Package: result.PkgPath, Err: err})
+		}
+	}
+	di.errs.Sort()
+	return di.errs.Err()
+}
+
+// generate run the generator of wire on the packages containing injectors, errors are reported to the context
+func (di *DIContext) generate(tags string) []wiregen.GenerateResult {
+	var paths []string
+	for _, inj := range di.injectors {
+		if !slices.Contains(paths, inj.Package()) {
+			paths = append(paths, inj.Package())
+		}
+	}
+	slices.Sort(paths)
+	results := make([]wiregen.GenerateResult, 0, len(paths))
+	for _, path := range paths {
+		if di.packageFailed(path) {
+			slog.Warn("skip generating package with injectors failing to resolve", "package", path)
+			continue
+		}
+		pkg, err := di.recheck(di.pkgs[path])
+		if err != nil {
+			var typeErr types.Error
+			pos := token.Position{}
+			if errors.As(err, &typeErr) {
+				pos = typeErr.Fset.Position(typeErr.Pos)
+				err = errors.New(typeErr.Msg)
+			}
+			di.report(pos, &GenerateError{Package: path, Err: err})
+			results = append(results, wiregen.GenerateResult{PkgPath: path, Errs: []error{err}})
+			continue
+		}
+		result := wiregen.GenerateFor(pkg, &wiregen.GenerateOptions{Tags: tags})
+		for _, err := range result.Errs {
+			pos, err := wiregen.Position(err)
+			di.report(pos, &GenerateError{Package: path, Err: err})
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// packageFailed report whether the package at path has injectors failing to resolve
+func (di *DIContext) packageFailed(path string) bool {
+	for ref := range di.failed {
+		if ref.importPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+// recheck parse and type check the refactored source of pkg again, as the wire.Build calls have been changed,
+// imported packages are taken from the loaded ones so that types are shared
+func (di *DIContext) recheck(pkg *decorator.Package) (*packages.Package, error) {
+	fset := di.loadConf.Fset
+	files := make([]*ast.File, 0, len(pkg.Syntax))
+	for _, file := range pkg.Syntax {
+		src, err := renderFile(pkg, file)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(fset, pkg.Decorator.Filenames[file], src, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	imports := map[string]*packages.Package{}
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Instances:  map[*ast.Ident]types.Instance{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Implicits:  map[ast.Node]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+		Scopes:     map[ast.Node]*types.Scope{},
+	}
+	config := &types.Config{
+		Sizes: pkg.TypesSizes,
+		Importer: importerFunc(func(path string) (*types.Package, error) {
+			imp := di.universe[path]
+			if imp == nil || imp.Types == nil {
+				return nil, fmt.Errorf("package %s is not loaded", path)
+			}
+			imports[path] = imp
+			return imp.Types, nil
+		}),
+	}
+	tpkg, err := config.Check(pkg.PkgPath, fset, files, info)
+	if err != nil {
+		return nil, err
+	}
+	checked := *pkg.Package
+	checked.Syntax = files
+	checked.Types = tpkg
+	checked.TypesInfo = info
+	checked.Imports = imports
+	return &checked, nil
+}
+
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
+
+// GenerateGo write plain go code for the injectors without the generator of wire,
+// each wireinject file x.go gets x_autowire_gen.go built with tag !wireinject,
+// it should be called after Process, files are not written in dry run,
+// files with injectors failing to resolve are skipped.
+func (di *DIContext) GenerateGo() error {
+	for _, file := range di.generateGo() {
+		if !di.conf.WillRewriteSource() {
+			slog.Info("dry run, skip writing generated file", "path", file.path)
+			continue
+		}
+		slog.Info("writing generated file", "path", file.path)
+		if err := os.WriteFile(file.path, file.src, 0o644); err != nil {
+			di.report(token.Position{}, &GenerateError{Package: file.pkg, Err: err})
+		}
+	}
+	di.errs.Sort()
+	return di.errs.Err()
+}
+
+type generatedFile struct {
+	pkg  string
+	path string
+	src  []byte
+}
+
+// generateGo render the generated files sorted by path, errors are reported to the context
+func (di *DIContext) generateGo() []generatedFile {
+	refs := di.sortedFiles()
+	var generated []generatedFile
+	for _, ref := range refs {
+		if di.failed[ref] {
+			slog.Warn("skip generating file with injectors failing to resolve", "file", ref.name)
+			continue
+		}
+		src, err := di.renderGenerated(ref)
+		if err != nil {
+			di.report(token.Position{Filename: ref.name}, &GenerateError{Package: ref.importPath, Err: err})
+			continue
+		}
+		path := strings.TrimSuffix(ref.name, ".go") + "_autowire_gen.go"
+		generated = append(generated, generatedFile{pkg: ref.importPath, path: path, src: src})
+	}
+	return generated
+}
+
+func (di *DIContext) renderGenerated(ref objRef) ([]byte, error) {
+	file, err := di.files[ref].Generate()
+	if err != nil {
+		return nil, err
+	}
+	return renderFile(di.pkgs[ref.importPath], file)
+}
@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+// test wire_gen.go is generated from the completed injectors without loading packages again
+func TestDIContext_Generate(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/args"); err != nil {
+		t.Fatal(err)
+	}
+	results := di.generate("")
+	if err := di.errs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expecting 1 result, got %d", len(results))
+	}
+	content := string(results[0].Content)
+	if !strings.Contains(content, "zhaoZhao := zhao.NewZhao()") || !strings.Contains(content, "func InitApp(cfg *Config, name string) *App {") {
+		t.Errorf("unexpected generated content:\n%s", content)
+	}
+}
+
+// test plain go code is generated for the injectors with the declarations of the wireinject file
+func TestDIContext_GenerateGo(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/args"); err != nil {
+		t.Fatal(err)
+	}
+	generated := di.generateGo()
+	if err := di.errs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(generated) != 1 || !strings.HasSuffix(generated[0].path, "wire_autowire_gen.go") {
+		t.Fatalf("unexpected generated files: %v", generated)
+	}
+	content := string(generated[0].src)
+	for _, want := range []string{"//go:build !wireinject", "zhaoZhao := zhao.NewZhao()", "NewApp(cfg, name, zhaoZhao)", "type Config struct"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expecting %q in generated content:\n%s", want, content)
+		}
+	}
+}
+
+// test errors of providers are checked and cleanup functions are called in reverse order
+func TestDIContext_GenerateGoCleanup(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/resource"); err != nil {
+		t.Fatal(err)
+	}
+	generated := di.generateGo()
+	if err := di.errs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	content := string(generated[0].src)
+	for _, want := range []string{"dbDb, cleanup, err := db.NewDB()", "cleanup()\n\t\treturn nil, nil, err", "return app, func() { cleanup() }, nil"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expecting %q in generated content:\n%s", want, content)
+		}
+	}
+}
+
+// test packages with injectors failing to resolve are skipped while the others are still generated
+func TestDIContext_GenerateFailed(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	err := di.Process("github.com/hauntedness/autowire/example/args", "github.com/hauntedness/autowire/example/broken")
+	if err == nil {
+		t.Fatal("expecting error for example/broken")
+	}
+	results := di.generate("")
+	if len(results) != 1 || !strings.HasSuffix(results[0].PkgPath, "example/args") || len(results[0].Errs) != 0 {
+		t.Fatalf("expecting only example/args generated, got %v", results)
+	}
+	generated := di.generateGo()
+	if len(generated) != 1 || generated[0].pkg != "github.com/hauntedness/autowire/example/args" {
+		t.Fatalf("expecting only example/args generated, got %v", generated)
+	}
+	if got := len(di.errs); got != 1 {
+		t.Errorf("expecting only the error of example/broken, got %v", di.errs)
+	}
+}
+
+// test the build tags are written space separated in the go:generate line of wire
+func TestDIContext_GenerateTags(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/args"); err != nil {
+		t.Fatal(err)
+	}
+	tags := map[string]string{
+		"a b": "github.com/google/wire/cmd/wire gen -tags \"a b\"\n",
+		"":    "github.com/google/wire/cmd/wire\n",
+	}
+	for tag, want := range tags {
+		results := di.generate(tag)
+		if len(results) != 1 || !strings.Contains(string(results[0].Content), want) {
+			t.Errorf("expecting %q in generated content with tags %q", want, tag)
+		}
+	}
+}
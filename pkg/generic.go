@@ -0,0 +1,136 @@
+package pkg
+
+import (
+	"cmp"
+	"go/ast"
+	"go/types"
+	"log/slog"
+	"slices"
+
+	"github.com/hauntedness/autowire/pkg/comm"
+)
+
+// instantiate create instances of the generic providers whose output can be unified with the type of bean,
+// e.g. NewRepo[User] for Repo[User] from func NewRepo[T any]() *Repo[T], the instances are indexed as other providers
+func (di *DIContext) instantiate(bean *comm.Bean) {
+	slices.SortFunc(di.generics, func(a, b *comm.Provider) int {
+		return cmp.Compare(a.FullName(), b.FullName())
+	})
+	for _, g := range di.generics {
+		sig := g.Func().Type().(*types.Signature)
+		bound := map[*types.TypeParam]types.Type{}
+		if !unify(sig.Results().At(0).Type(), bean.Type(), bound) {
+			continue
+		}
+		targs := make([]types.Type, 0, sig.TypeParams().Len())
+		for i := 0; i < sig.TypeParams().Len(); i++ {
+			t, ok := bound[sig.TypeParams().At(i)]
+			if !ok {
+				// only used by params, can not be inferred from the output
+				break
+			}
+			targs = append(targs, t)
+		}
+		if len(targs) != sig.TypeParams().Len() {
+			continue
+		}
+		if _, err := di.instance(g.Func(), targs); err != nil {
+			slog.Debug("generic provider can not be instantiated", "func", g.FullName(), "bean", bean.Id(), "err", err)
+		}
+	}
+}
+
+// instance return the provider of the generic function fn instantiated with targs, with directives of fn applied
+func (di *DIContext) instance(fn *types.Func, targs []types.Type) (*comm.Provider, error) {
+	p, err := comm.NewInstance(fn, targs)
+	if err != nil {
+		return nil, err
+	}
+	if cached := di.instances[p.String()]; cached != nil {
+		return cached, nil
+	}
+	p = applyDirectives(p, di.directives[fn])
+	di.instances[p.String()] = p
+	typ := p.Provide().Type()
+	list, _ := di.index.At(typ).([]*comm.Provider)
+	di.index.Set(typ, append(list, p))
+	return p, nil
+}
+
+// instanceArg parse the explicitly instantiated function in wire.Build, e.g. repo.NewRepo[model.User],
+// return nil if expr is not
+func (di *DIContext) instanceArg(info *types.Info, expr ast.Expr) *comm.Provider {
+	var x ast.Expr
+	switch e := expr.(type) {
+	case *ast.IndexExpr:
+		x = e.X
+	case *ast.IndexListExpr:
+		x = e.X
+	default:
+		return nil
+	}
+	fn, ok := qualifiedIdentObject(info, x).(*types.Func)
+	if !ok {
+		return nil
+	}
+	ident, ok := x.(*ast.Ident)
+	if sel, isSel := x.(*ast.SelectorExpr); isSel {
+		ident, ok = sel.Sel, true
+	}
+	if !ok {
+		return nil
+	}
+	inst := info.Instances[ident]
+	targs := make([]types.Type, 0, inst.TypeArgs.Len())
+	for i := 0; i < inst.TypeArgs.Len(); i++ {
+		targs = append(targs, inst.TypeArgs.At(i))
+	}
+	di.requirePackage(fn.Pkg().Path())
+	p, err := di.instance(fn, targs)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// unify match the type x containing type parameters against y, the type parameters are bound to the matched parts of y
+func unify(x, y types.Type, bound map[*types.TypeParam]types.Type) bool {
+	switch x := x.(type) {
+	case *types.TypeParam:
+		if t, ok := bound[x]; ok {
+			return types.Identical(t, y)
+		}
+		bound[x] = y
+		return true
+	case *types.Pointer:
+		y, ok := y.(*types.Pointer)
+		return ok && unify(x.Elem(), y.Elem(), bound)
+	case *types.Slice:
+		y, ok := y.(*types.Slice)
+		return ok && unify(x.Elem(), y.Elem(), bound)
+	case *types.Array:
+		y, ok := y.(*types.Array)
+		return ok && x.Len() == y.Len() && unify(x.Elem(), y.Elem(), bound)
+	case *types.Map:
+		y, ok := y.(*types.Map)
+		return ok && unify(x.Key(), y.Key(), bound) && unify(x.Elem(), y.Elem(), bound)
+	case *types.Chan:
+		y, ok := y.(*types.Chan)
+		return ok && x.Dir() == y.Dir() && unify(x.Elem(), y.Elem(), bound)
+	case *types.Named:
+		y, ok := y.(*types.Named)
+		if !ok || x.TypeArgs().Len() == 0 {
+			return ok && types.Identical(x, y)
+		}
+		if x.Origin().Obj() != y.Origin().Obj() || x.TypeArgs().Len() != y.TypeArgs().Len() {
+			return false
+		}
+		for i := 0; i < x.TypeArgs().Len(); i++ {
+			if !unify(x.TypeArgs().At(i), y.TypeArgs().At(i), bound) {
+				return false
+			}
+		}
+		return true
+	}
+	return types.Identical(x, y)
+}
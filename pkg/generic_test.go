@@ -0,0 +1,53 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDIContext_Generic(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/generic"); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := di.Diff(&sb); err != nil {
+		t.Fatal(err)
+	}
+	// instances of the generic type are distinct beans, each provided by an instance of NewRepo
+	want := "+	wire.Build(NewService, repo.NewRepo[model.Order], repo.NewRepo[model.User], repo.NewDB)"
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("expecting diff contains %q, got:\n%s", want, sb.String())
+	}
+	generated := di.generateGo()
+	if err := di.errs.Err(); err != nil {
+		t.Fatal(err)
+	}
+	content := string(generated[0].src)
+	for _, want := range []string{"userRepo := repo.NewRepo[model.User](db)", "orderRepo := repo.NewRepo[model.Order](db)"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expecting %q in generated content:\n%s", want, content)
+		}
+	}
+}
+
+// test the instances written in wire.Build are parsed, so nothing is added again
+func TestDIContext_GenericComplete(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	path := "github.com/hauntedness/autowire/example/generic"
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
+	inj := di.injectors[objRef{importPath: path, name: "InitAudit"}]
+	assertNotNil(t, inj)
+	if added := inj.Added(); len(added) != 0 {
+		t.Errorf("expecting nothing added, got %v", added)
+	}
+	found := false
+	for _, p := range inj.Providers() {
+		found = found || p.FullName() == "github.com/hauntedness/autowire/example/generic/repo.NewRepo[github.com/hauntedness/autowire/example/generic/model.User]" && inj.IsOrigin(p)
+	}
+	if !found {
+		t.Errorf("expecting repo.NewRepo[model.User] in original wire.Build, got %v", inj.Providers())
+	}
+}
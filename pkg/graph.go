@@ -0,0 +1,202 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/types"
+	"io"
+	"strings"
+
+	"github.com/hauntedness/autowire/pkg/comm"
+)
+
+// kinds of graph node
+const (
+	InjectorNode = "injector"
+	ProviderNode = "provider"
+	ArgumentNode = "argument"
+	BeanNode     = "bean"
+)
+
+// Graph is the dependency graph of a resolved injector,
+// edges go from a bean to the provider requiring it and from a provider to the bean it provides,
+// the output bean points to the injector
+type Graph struct {
+	Injector string       `json:"injector"` // qualified name of the injector, e.g. github.com/x/y.InitApp
+	Nodes    []*GraphNode `json:"nodes"`
+	Edges    []*GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"` // short name, types are qualified by package name
+	Name  string `json:"name"`  // full name, types are qualified by package path
+	// for providers, whether it is in the original wire.Build call rather than added by autowire
+	Origin bool `json:"origin,omitempty"`
+}
+
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Graph load the packages matching patterns, resolve their injectors and return their dependency graphs sorted by injector,
+// the source is not refactored, graphs of injectors failing to resolve are returned as they are along with the errors
+func (di *DIContext) Graph(patterns ...string) ([]*Graph, error) {
+	err := di.Resolve(patterns...)
+	injectors := di.sortedInjectors()
+	graphs := make([]*Graph, 0, len(injectors))
+	for _, inj := range injectors {
+		graphs = append(graphs, newGraph(inj))
+	}
+	return graphs, err
+}
+
+// Name report the unqualified name of the injector
+func (g *Graph) Name() string {
+	return g.Injector[strings.LastIndex(g.Injector, ".")+1:]
+}
+
+func newGraph(inj *comm.Injector) *Graph {
+	g := &Graph{Injector: inj.Package() + "." + inj.Name()}
+	qf := relativeTo(inj.Package())
+	node := func(kind, label, name string, origin bool) *GraphNode {
+		n := &GraphNode{ID: fmt.Sprintf("n%d", len(g.Nodes)), Kind: kind, Label: label, Name: name, Origin: origin}
+		g.Nodes = append(g.Nodes, n)
+		return n
+	}
+	beans := map[comm.BeanId]*GraphNode{}
+	bean := func(b *comm.Bean) *GraphNode {
+		if n := beans[b.Id()]; n != nil {
+			return n
+		}
+		n := node(BeanNode, beanLabel(b, qf), b.Id(), false)
+		beans[b.Id()] = n
+		return n
+	}
+	edge := func(from, to *GraphNode) {
+		g.Edges = append(g.Edges, &GraphEdge{From: from.ID, To: to.ID})
+	}
+	root := node(InjectorNode, inj.Name(), g.Injector, false)
+	edge(bean(inj.Output()), root)
+	for _, p := range inj.Providers() {
+		kind := ProviderNode
+		if p.Kind() == comm.ArgProvider {
+			kind = ArgumentNode
+		}
+		n := node(kind, p.Label(qf), p.FullName(), inj.IsOrigin(p))
+		for _, b := range p.Require() {
+			edge(bean(b), n)
+		}
+		for _, b := range p.ProvideAll() {
+			edge(n, bean(b))
+		}
+	}
+	return g
+}
+
+// WriteGraph write graphs to w in format dot, mermaid or json
+func WriteGraph(w io.Writer, format string, graphs []*Graph) error {
+	switch format {
+	case "dot":
+		return writeDot(w, graphs)
+	case "mermaid":
+		return writeMermaid(w, graphs)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(graphs)
+	default:
+		return fmt.Errorf("unknown graph format %q, should be one of dot, mermaid, json", format)
+	}
+}
+
+// writeDot write each graph as a digraph, providers added by autowire are dashed
+func writeDot(w io.Writer, graphs []*Graph) error {
+	b := &strings.Builder{}
+	for _, g := range graphs {
+		fmt.Fprintf(b, "digraph %q {\n", g.Name())
+		fmt.Fprintf(b, "\trankdir=LR;\n")
+		for _, n := range g.Nodes {
+			var attrs string
+			switch n.Kind {
+			case InjectorNode:
+				attrs = "shape=doubleoctagon"
+			case BeanNode:
+				attrs = "shape=ellipse"
+			case ArgumentNode:
+				attrs = "shape=parallelogram"
+			default:
+				attrs = "shape=box"
+				if !n.Origin {
+					attrs += ", style=dashed"
+				}
+			}
+			fmt.Fprintf(b, "\t%s [label=%q, %s];\n", n.ID, n.Label, attrs)
+		}
+		for _, e := range g.Edges {
+			fmt.Fprintf(b, "\t%s -> %s;\n", e.From, e.To)
+		}
+		fmt.Fprintf(b, "}\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeMermaid write each graph as a flowchart, providers added by autowire are in class added
+func writeMermaid(w io.Writer, graphs []*Graph) error {
+	b := &strings.Builder{}
+	for i, g := range graphs {
+		if i > 0 {
+			fmt.Fprintln(b)
+		}
+		fmt.Fprintf(b, "%%%% %s\n", g.Injector)
+		fmt.Fprintf(b, "flowchart LR\n")
+		var added []string
+		for _, n := range g.Nodes {
+			label := strings.ReplaceAll(n.Label, `"`, "#quot;")
+			switch n.Kind {
+			case InjectorNode:
+				fmt.Fprintf(b, "\t%s[[\"%s\"]]\n", n.ID, label)
+			case BeanNode:
+				fmt.Fprintf(b, "\t%s([\"%s\"])\n", n.ID, label)
+			case ArgumentNode:
+				fmt.Fprintf(b, "\t%s[/\"%s\"/]\n", n.ID, label)
+			default:
+				fmt.Fprintf(b, "\t%s[\"%s\"]\n", n.ID, label)
+				if !n.Origin {
+					added = append(added, n.ID)
+				}
+			}
+		}
+		for _, e := range g.Edges {
+			fmt.Fprintf(b, "\t%s --> %s\n", e.From, e.To)
+		}
+		if len(added) > 0 {
+			fmt.Fprintf(b, "\tclassDef added stroke-dasharray: 5 5\n")
+			fmt.Fprintf(b, "\tclass %s added\n", strings.Join(added, ","))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// beanLabel is the type of b qualified by qf, with the qualifier of b if any
+func beanLabel(b *comm.Bean, qf types.Qualifier) string {
+	label := types.TypeString(b.Type(), qf)
+	if b.Qualifier() != "" {
+		label += " (" + b.Qualifier() + ")"
+	}
+	return label
+}
+
+// relativeTo qualify types by package name except those in package path
+func relativeTo(path string) types.Qualifier {
+	return func(pkg *types.Package) string {
+		if pkg.Path() == path {
+			return ""
+		}
+		return pkg.Name()
+	}
+}
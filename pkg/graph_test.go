@@ -0,0 +1,36 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDIContext_Graph(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	graphs, err := di.Graph("github.com/hauntedness/autowire/example/args")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graphs) != 1 || graphs[0].Name() != "InitApp" {
+		t.Fatalf("expecting graph of InitApp, got %v", graphs)
+	}
+	kinds := map[string]string{}
+	origin := map[string]bool{}
+	for _, n := range graphs[0].Nodes {
+		kinds[n.Label] = n.Kind
+		origin[n.Label] = n.Origin
+	}
+	if kinds["cfg *Config"] != ArgumentNode || kinds["*zhao.Zhao"] != BeanNode || kinds["NewApp"] != ProviderNode {
+		t.Errorf("unexpected nodes: %v", kinds)
+	}
+	if !origin["NewApp"] || origin["zhao.NewZhao"] {
+		t.Errorf("NewApp should be origin while zhao.NewZhao is added by autowire: %v", origin)
+	}
+	b := &strings.Builder{}
+	if err := WriteGraph(b, "dot", graphs); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `[label="zhao.NewZhao", shape=box, style=dashed]`) {
+		t.Errorf("added provider should be dashed:\n%s", b)
+	}
+}
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// test added providers are in topological order, and arguments are put on their own lines when the call is long
+func TestDIContext_Order(t *testing.T) {
+	want := `	wire.Build(
+		NewApp,
+		wire.Struct(new(Server), "*"),
+		liu.NewLiu,
+		guan.NewGuan,
+		zhang.NewZhang,
+		yanyan.NewYanYan,
+		zhao.NewZhao,
+	)`
+	for range 3 {
+		di := NewDIContext(&DefaultProcessConfigurer{StructProvider: true}, nil)
+		if err := di.Process("github.com/hauntedness/autowire/example/structs"); err != nil {
+			t.Fatal(err)
+		}
+		for _, file := range di.files {
+			content, err := renderFile(di.pkgs[file.Package()], file.File())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(content), want) {
+				t.Fatalf("expecting %q in refactored file:\n%s", want, content)
+			}
+		}
+	}
+}
+
+// test all arguments are sorted with Sort, a provider set is placed by its first provider
+func TestDIContext_Sort(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{Sort: true}, nil)
+	if err := di.Process("github.com/hauntedness/autowire/example/set"); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := di.Diff(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := "+	wire.Build(NewService, zhao.NewZhao, store.ProviderSet, store.NewConfig)"
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("expecting diff contains %q, got:\n%s", want, sb.String())
+	}
+}
+
+// test injectors are resolved in order of package path and name, so lazy loading does not depend on map order
+func TestDIContext_InjectorOrder(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	_ = di.Resolve("github.com/hauntedness/autowire/example/primitive", "github.com/hauntedness/autowire/example/args")
+	var names []string
+	for _, e := range di.elections {
+		name := e.Injector.String()
+		if len(names) == 0 || names[len(names)-1] != name {
+			names = append(names, name)
+		}
+	}
+	if len(names) < 2 || !slices.IsSorted(names) {
+		t.Errorf("expecting injectors resolved in sorted order, got %v", names)
+	}
+}
+
+// test imports of the same name are aliased in source order of the injectors, the same in every run
+func TestDIContext_ImportAliasOrder(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+		if err := di.Process("github.com/hauntedness/autowire/example/dup"); err != nil {
+			t.Fatal(err)
+		}
+		var sb strings.Builder
+		if err := di.Diff(&sb); err != nil {
+			t.Fatal(err)
+		}
+		diff := sb.String()
+		for _, want := range []string{"+	wire.Build(NewX, store.NewA)", "+	wire.Build(NewY, bstore.NewB)"} {
+			if !strings.Contains(diff, want) {
+				t.Fatalf("expecting diff contains %q, got:\n%s", want, diff)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+// test providers requiring basic types are used only when the injector gives them
+func TestDIContext_Primitive(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	err := di.Resolve("github.com/hauntedness/autowire/example/primitive")
+	var unresolvable *UnresolvablePrimitiveError
+	if !errors.As(err, &unresolvable) || len(unresolvable.Candidates) != 1 {
+		t.Fatalf("expecting UnresolvablePrimitiveError for InitLobby, got %v", err)
+	}
+	winners := map[string]string{
+		"InitHall":        "NewLiu2", // name is an argument
+		"InitHallValue":   "NewLiu2", // name is given by wire.Value
+		"InitHallDefault": "NewLiu",
+	}
+	elections := di.Elections("*liu.Liu")
+	if len(elections) != len(winners) {
+		t.Fatalf("expecting %d elections, got %d", len(winners), len(elections))
+	}
+	for _, e := range elections {
+		want := winners[e.Injector.Name()]
+		if e.Winner == nil || e.Winner.Name() != want {
+			t.Errorf("expecting %s elected for %s, got %v", want, e.Injector.Name(), e.Winner)
+		}
+	}
+}
+
+// test the struct fallback does not hide a provider requiring a primitive the injector does not give
+func TestDIContext_PrimitiveStruct(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{StructProvider: true}, nil)
+	err := di.Resolve("github.com/hauntedness/autowire/example/primitive")
+	var unresolvable *UnresolvablePrimitiveError
+	if !errors.As(err, &unresolvable) || len(unresolvable.Candidates) != 1 {
+		t.Fatalf("expecting UnresolvablePrimitiveError for InitLobby, got %v", err)
+	}
+	for _, e := range di.Elections("*greet.Greeter") {
+		if e.Winner != nil {
+			t.Errorf("expecting no provider elected for %s, got %s", e.Injector.Name(), e.Winner)
+		}
+	}
+}
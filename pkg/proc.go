@@ -3,30 +3,93 @@ package pkg
 import (
 	"go/ast"
 	"go/build/constraint"
+	"go/token"
 	"go/types"
+	"log/slog"
+	"path/filepath"
+	"strings"
 
 	"github.com/dave/dst"
 	"github.com/dave/dst/decorator"
-	"github.com/hauntedness/autowire/conf"
 	"github.com/hauntedness/autowire/pkg/comm"
-	"github.com/hauntedness/autowire/pkg/util"
+	"golang.org/x/tools/go/ast/astutil"
+	"golang.org/x/tools/go/packages"
 )
 
-// load package by path
-func loadPackage(path string) *decorator.Package {
-	pkgs, err := decorator.Load(conf.DefaultConf, path)
+// loadPackages load packages matching patterns together with all their dependencies in a single call,
+// patterns can be import path, relative path or ./...
+// every loaded package is added to the universe so types are identical wherever they are referenced,
+// errors of the matched packages are reported to the context and ok is false if there is any
+func (di *DIContext) loadPackages(patterns ...string) (pkgs []*packages.Package, ok bool) {
+	escaped := make([]string, len(patterns))
+	for i := range patterns {
+		escaped[i] = "pattern=" + patterns[i]
+	}
+	pkgs, err := packages.Load(di.loadConf, escaped...)
 	if err != nil {
-		panic(err)
+		di.report(token.Position{}, &LoadError{Pattern: strings.Join(patterns, " "), Msg: err.Error()})
+		return nil, false
+	}
+	if len(pkgs) == 0 {
+		di.report(token.Position{}, &LoadError{Pattern: strings.Join(patterns, " "), Msg: "no package matched"})
+		return nil, false
+	}
+	packages.Visit(pkgs, func(pkg *packages.Package) bool {
+		if _, ok := di.universe[pkg.PkgPath]; ok {
+			return false
+		}
+		di.universe[pkg.PkgPath] = pkg
+		return true
+	}, nil)
+	ok = true
+	for _, pkg := range pkgs {
+		if !di.reportPackageErrors(pkg.PkgPath, pkg) {
+			ok = false
+		}
 	}
-	return pkgs[0]
+	return pkgs, ok
+}
+
+// decorate wrap pkg as decorator.Package and convert its syntax to dst
+func decorate(pkg *packages.Package) (*decorator.Package, error) {
+	p := &decorator.Package{Package: pkg, Imports: map[string]*decorator.Package{}}
+	if len(pkg.Syntax) == 0 {
+		return p, nil
+	}
+	// only decorate files in GoFiles, Syntax may also contain preprocessed cgo files
+	goFiles := make(map[string]bool, len(pkg.GoFiles))
+	for _, name := range pkg.GoFiles {
+		goFiles[name] = true
+	}
+	p.Decorator = decorator.NewDecoratorFromPackage(pkg)
+	for _, f := range pkg.Syntax {
+		if !goFiles[pkg.Fset.File(f.Pos()).Name()] {
+			continue
+		}
+		file, err := p.Decorator.DecorateFile(f)
+		if err != nil {
+			return nil, err
+		}
+		p.Syntax = append(p.Syntax, file)
+	}
+	p.Dir = filepath.Dir(pkg.Fset.File(pkg.Syntax[0].Pos()).Name())
+	return p, nil
 }
 
 func (di *DIContext) loadProviderAndInjector(pkg *decorator.Package, conf *LoadConfig) {
+	dirs := funcDirectives(pkg)
+	for fn, d := range dirs {
+		di.directives[fn] = d
+	}
 	for id, obj := range pkg.TypesInfo.Defs {
 		if id == nil || obj == nil {
 			continue
 		}
-		di.loadProvider(obj)
+		fn, ok := obj.(*types.Func)
+		if !ok {
+			continue
+		}
+		di.loadProvider(fn, dirs[fn])
 
 	}
 	if conf.LoadMode.NeedMode(LoadInjector) {
@@ -51,7 +114,9 @@ func (di *DIContext) loadInjector(pkg *decorator.Package, file *dst.File, decl d
 	if funcDecl, ok := dec.Ast.Nodes[decl].(*ast.FuncDecl); ok {
 		callExpr, err := findInjectorBuild(pkg.TypesInfo, funcDecl)
 		if err != nil {
-			panic(err)
+			pos := pkg.Fset.Position(funcDecl.Pos())
+			di.report(pos, &InjectorError{Injector: pkg.PkgPath + "." + funcDecl.Name.Name, Err: err})
+			return
 		}
 		if callExpr == nil {
 			// funcDecl is not a build call
@@ -60,19 +125,22 @@ func (di *DIContext) loadInjector(pkg *decorator.Package, file *dst.File, decl d
 		funcObj := pkg.TypesInfo.Defs[funcDecl.Name]
 		fn := funcObj.(*types.Func)
 		origin := make(map[string]*comm.Provider)
-		auto := true // whether apply autowire to this injector
-		for _, e := range callExpr.Args {
-			obj := pkg.TypesInfo.ObjectOf(util.Unwrap(e))
-			if f, ok := obj.(*types.Func); ok {
-				p := comm.NewProvider(f)
-				origin[f.String()] = p
-				ref := objRef{importPath: p.Package(), name: p.Name()}
-				if _, ok := di.providers[ref]; !ok {
-					di.providers[ref] = p
-				}
-			} else {
-				auto = false
+		// whether apply autowire to this injector
+		var providers []*comm.Provider
+		auto := true
+		args := make([][]*comm.Provider, len(callExpr.Args))
+		for i, arg := range callExpr.Args {
+			list, ok := di.parseWireArgs(pkg, []ast.Expr{arg})
+			providers = append(providers, list...)
+			args[i] = list
+			auto = auto && ok
+		}
+		for _, p := range providers {
+			origin[p.String()] = p
+			if p.Kind() != comm.FuncProvider || p.TypeArgs() != nil {
+				continue
 			}
+			di.addProvider(objRef{importPath: p.Package(), name: p.Name()}, p)
 		}
 		ref := objRef{
 			importPath: fn.Pkg().Path(),
@@ -80,41 +148,82 @@ func (di *DIContext) loadInjector(pkg *decorator.Package, file *dst.File, decl d
 		}
 		dstExpr := dec.Dst.Nodes[callExpr].(*dst.CallExpr)
 		inj := comm.NewInjector(fn, origin, dstExpr, auto)
+		for i, arg := range callExpr.Args {
+			// provider functions, possibly instantiated, rather than sets, binds, structs or values
+			arg = astutil.Unparen(arg)
+			_, isFunc := qualifiedIdentObject(pkg.TypesInfo, arg).(*types.Func)
+			isFunc = isFunc || di.instanceArg(pkg.TypesInfo, arg) != nil
+			inj.AddBuildArg(dstExpr.Args[i], args[i], isFunc)
+		}
 		di.injectors[ref] = inj
 		fileName := dec.Filenames[file]
 		fileRef := objRef{importPath: fn.Pkg().Path(), name: fileName}
 		wireFile := di.files[fileRef]
 		if wireFile == nil {
 			wireFile = comm.NewFile(file, fn.Pkg().Path())
+			if pkg.Module != nil {
+				wireFile.SetModule(pkg.Module.Path)
+			}
 			di.files[fileRef] = wireFile
 		}
 		wireFile.AddInjector(inj)
 	}
 }
 
-func (di *DIContext) loadProvider(obj types.Object) {
-	switch fn := obj.(type) {
-	case *types.Func:
-		switch sig := obj.Type().(type) {
-		case *types.Signature:
-			// just possibly a provider, so use wire validate the func
-			_, err := funcOutput(sig)
-			if err != nil {
-				return
-			}
-			// and of course, we have our own validate func
-			if !di.conf.ProviderPredicate(fn) {
-				return
-			}
-			ref := objRef{
-				importPath: obj.Pkg().Path(),
-				name:       obj.Name(),
-			}
-			di.providers[ref] = comm.NewProvider(fn)
-		default:
+// loadProvider add fn as provider if it is accepted by ProviderPredicate or marked by directives
+func (di *DIContext) loadProvider(fn *types.Func, dirs directives) {
+	ref := objRef{importPath: fn.Pkg().Path(), name: fn.Name()}
+	if dirs.ignore {
+		di.ignored[ref] = true
+		return
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() != nil {
+		return
+	}
+	// just possibly a provider, so use wire validate the func
+	if _, err := funcOutput(sig); err != nil {
+		if dirs.provider || dirs.primary {
+			slog.Warn("function marked by directive is not a valid provider", "func", fn.FullName(), "err", err)
 		}
-	default:
+		return
+	}
+	// and of course, we have our own validate func
+	if !dirs.provider && !dirs.primary && !di.conf.ProviderPredicate(fn) {
+		return
+	}
+	di.addProvider(ref, newFuncProvider(fn, dirs))
+}
+
+// funcProvider return the provider of fn with its directives applied,
+// the declaring package is loaded first so that the directives are known
+func (di *DIContext) funcProvider(fn *types.Func) *comm.Provider {
+	ref := objRef{importPath: fn.Pkg().Path(), name: fn.Name()}
+	if p := di.providers[ref]; p != nil {
+		return p
+	}
+	di.requirePackage(ref.importPath)
+	if p := di.providers[ref]; p != nil {
+		return p
+	}
+	return newFuncProvider(fn, di.directives[fn])
+}
+
+// addProvider add p to context and index it by the bean it provides,
+// p is ignored if ref is already added or excluded by //autowire:ignore
+func (di *DIContext) addProvider(ref objRef, p *comm.Provider) {
+	if _, ok := di.providers[ref]; ok || di.ignored[ref] {
+		return
+	}
+	di.providers[ref] = p
+	if p.Generic() {
+		// the output has type parameters, it is indexed by instances
+		di.generics = append(di.generics, p)
+		return
 	}
+	typ := p.Provide().Type()
+	list, _ := di.index.At(typ).([]*comm.Provider)
+	di.index.Set(typ, append(list, p))
 }
 
 func checkBuildConstraint(file *ast.File) bool {
@@ -1,97 +1,426 @@
 package pkg
 
 import (
+	"cmp"
+	"fmt"
+	"go/token"
+	"go/types"
 	"log/slog"
+	"slices"
 
 	"github.com/dave/dst/decorator"
+	"github.com/hauntedness/autowire/conf"
 	"github.com/hauntedness/autowire/pkg/comm"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/types/typeutil"
 )
 
 type DIContext struct {
-	conf      ProcessConfigurer
-	pkgs      map[string]*decorator.Package
-	files     map[objRef]*comm.WireFile
-	injectors map[objRef]*comm.Injector
-	providers map[objRef]*comm.Provider // maybe here better be a map[BeanId]map[FuncId]*Provider
+	conf        ProcessConfigurer
+	loadConf    *packages.Config
+	universe    map[string]*packages.Package // all packages loaded, including dependencies
+	pkgs        map[string]*decorator.Package
+	files       map[objRef]*comm.WireFile
+	injectors   map[objRef]*comm.Injector
+	providers   map[objRef]*comm.Provider
+	generics    []*comm.Provider          // generic providers, instantiated on demand
+	instances   map[string]*comm.Provider // instances of generic providers by String
+	ignored     map[objRef]bool           // functions excluded by //autowire:ignore
+	directives  map[*types.Func]directives
+	allowedPkgs map[string]bool // cache of PackagePredicate by package path
+	index       typeutil.Map    // providers indexed by the type they provide, value is []*comm.Provider
+	impls       typeutil.Map    // paths of the packages declaring implementations by interface type, see implementers
+	sets        map[ProviderSetID]*providerSet
+	elections   []*Election     // decisions made in resolving injectors
+	failed      map[objRef]bool // files with injectors failing to resolve, skipped in generating
+	errs        ErrorList
 }
 
-func NewDIContext(conf ProcessConfigurer) *DIContext {
-	if conf == nil {
-		conf = &DefaultProcessConfigurer{willRewriteSource: true}
+// NewDIContext create a DIContext,
+// when procConf is nil, the DefaultProcessConfigurer is used and the source will be rewritten,
+// when loadConf is nil, conf.DefaultConf is used to load packages,
+// a file set is created if loadConf has none, so positions of all loaded packages are comparable.
+func NewDIContext(procConf ProcessConfigurer, loadConf *packages.Config) *DIContext {
+	if procConf == nil {
+		procConf = &DefaultProcessConfigurer{RewriteSource: true}
+	}
+	if loadConf == nil {
+		loadConf = conf.DefaultConf
+	}
+	if loadConf.Fset == nil {
+		copied := *loadConf
+		copied.Fset = token.NewFileSet()
+		loadConf = &copied
 	}
 	return &DIContext{
-		conf:      conf,
-		pkgs:      map[string]*decorator.Package{},
-		files:     map[objRef]*comm.WireFile{},
-		injectors: map[objRef]*comm.Injector{},
-		providers: map[objRef]*comm.Provider{},
+		conf:        procConf,
+		loadConf:    loadConf,
+		universe:    map[string]*packages.Package{},
+		pkgs:        map[string]*decorator.Package{},
+		files:       map[objRef]*comm.WireFile{},
+		injectors:   map[objRef]*comm.Injector{},
+		providers:   map[objRef]*comm.Provider{},
+		instances:   map[string]*comm.Provider{},
+		ignored:     map[objRef]bool{},
+		directives:  map[*types.Func]directives{},
+		allowedPkgs: map[string]bool{},
+		sets:        map[ProviderSetID]*providerSet{},
+		failed:      map[objRef]bool{},
 	}
 }
 
-func (di *DIContext) Process(path string) {
-	pkg := loadPackage(path)
-	di.pkgs[pkg.PkgPath] = pkg
-	// parse
-	config := &LoadConfig{
-		LoadMode: LoadProvider | LoadInjector,
+// Process load the packages matching patterns, complete their injectors and refactor the source.
+// The returned error is an ErrorList if not nil,
+// injectors which fail to resolve are reported and left untouched.
+func (di *DIContext) Process(patterns ...string) error {
+	if !di.load(patterns...) {
+		return di.errs.Err()
 	}
-	// load entry package
-	di.loadProviderAndInjector(pkg, config)
 
 	di.doInject()
 
 	di.refactor()
+
+	di.errs.Sort()
+	return di.errs.Err()
+}
+
+// Check load the packages matching patterns and resolve their injectors without refactoring the source,
+// each injector whose wire.Build call miss providers is reported as an IncompleteInjectorError
+func (di *DIContext) Check(patterns ...string) error {
+	if !di.load(patterns...) {
+		return di.errs.Err()
+	}
+
+	di.doInject()
+
+	for _, inj := range di.sortedInjectors() {
+		if !inj.Auto() {
+			continue
+		}
+		added := inj.Added()
+		if len(added) == 0 {
+			continue
+		}
+		missing := make([]string, 0, len(added))
+		for _, p := range added {
+			missing = append(missing, p.FullName())
+		}
+		di.report(di.position(inj.Pos()), &IncompleteInjectorError{Injector: inj.String(), Providers: missing})
+	}
+	di.errs.Sort()
+	return di.errs.Err()
+}
+
+// Resolve load the packages matching patterns and resolve their injectors without refactoring the source
+func (di *DIContext) Resolve(patterns ...string) error {
+	if di.load(patterns...) {
+		di.doInject()
+	}
+	di.errs.Sort()
+	return di.errs.Err()
+}
+
+// load the entry packages matching patterns, with their providers and injectors
+func (di *DIContext) load(patterns ...string) bool {
+	config := &LoadConfig{
+		LoadMode: LoadProvider | LoadInjector,
+	}
+	roots, ok := di.loadPackages(patterns...)
+	if !ok {
+		return false
+	}
+	for _, root := range roots {
+		// the root may be required by injectors of another root already
+		pkg := di.pkgs[root.PkgPath]
+		if pkg == nil {
+			var err error
+			pkg, err = decorate(root)
+			if err != nil {
+				di.report(token.Position{}, &LoadError{Pattern: root.PkgPath, Msg: err.Error()})
+				return false
+			}
+			di.pkgs[pkg.PkgPath] = pkg
+		}
+		di.loadProviderAndInjector(pkg, config)
+	}
+	return true
 }
 
-// doInject process each injector,
+// doInject process each injector in the order of sortedInjectors,
+// as electing providers loads packages lazily, the result would depend on the order otherwise
 func (di *DIContext) doInject() {
-	for _, inj := range di.injectors {
-		// until all required is provided
-		// while it is possible that some providers miss
-		for i := range [1000]struct{}{} {
-			m := inj.Require()
-			if len(m) == 0 {
-				break
+	for _, inj := range di.sortedInjectors() {
+		if !inj.Auto() {
+			continue
+		}
+		if err := di.resolve(inj); err != nil {
+			inj.SetAuto(false)
+			pos := di.position(inj.Pos())
+			di.failed[objRef{importPath: inj.Package(), name: pos.Filename}] = true
+			di.report(pos, err)
+			continue
+		}
+		if mode := di.conf.PruneMode(); mode != PruneNone {
+			for _, p := range inj.Prune(mode == PruneAll) {
+				slog.Info("provider pruned", "injector", inj.String(), "provider", p.String())
 			}
-			if i == 999 {
-				slog.Warn("still could not find privder after trying many times", "round", i)
-				break
+		}
+	}
+}
+
+// resolve add providers to inj until all required beans are provided
+func (di *DIContext) resolve(inj *comm.Injector) error {
+	// providers written in wire.Build must also fit the injector signature, or wire fails later
+	for _, p := range inj.Providers() {
+		if ok, reason := inj.Accept(p); !ok && inj.IsOrigin(p) {
+			return &InjectorError{Injector: inj.String(), Err: fmt.Errorf("provider %s %s", p.FullName(), reason)}
+		}
+	}
+	// until all required is provided
+	// while it is possible that some providers miss
+	for i := range [1000]struct{}{} {
+		m, err := inj.Require()
+		if err != nil {
+			return err
+		}
+		if len(m) == 0 {
+			break
+		}
+		if i == 999 {
+			slog.Warn("still could not find privder after trying many times", "round", i)
+			break
+		}
+		for _, bean := range m {
+			e := &Election{Injector: inj, Bean: bean, Candidates: map[string]*comm.Provider{}, fset: di.loadConf.Fset}
+			di.elections = append(di.elections, e)
+			if err := di.elect(e); err != nil {
+				e.Note = err.Error()
+				return err
 			}
-			for _, bean := range m {
-				path := bean.PkgPath()
-				if di.pkgs[path] == nil {
-					pkg := loadPackage(path)
-					di.pkgs[path] = pkg
-					di.loadProviderAndInjector(pkg, &LoadConfig{LoadMode: LoadProvider})
-				}
-				candidates := make(map[string]*comm.Provider)
-				// TODO here we need more efficient way
-				for _, p := range di.providers {
-					b := p.Provide()
-					if b.Identical(bean) {
-						candidates[p.String()] = p
-					}
-				}
-				p := di.conf.ProviderElect(inj, bean, candidates)
-				inj.AddProvider(p)
+		}
+	}
+	return nil
+}
+
+// elect find the provider of the bean and add it to the injector, the decision is recorded in e
+func (di *DIContext) elect(e *Election) error {
+	inj, bean := e.Injector, e.Bean
+	if bean.Primitive() {
+		// a string or slice can mean anything, so it is never searched
+		return &UnresolvablePrimitiveError{Injector: inj.String(), Bean: bean.Id()}
+	}
+	// predeclared types such as error belong to no package
+	if path := bean.PkgPath(); path != "" {
+		if _, ok := di.requirePackage(path); !ok {
+			return &ProviderNotFoundError{Injector: inj.String(), Bean: bean.Id()}
+		}
+	}
+	if bean.Qualifier() != "" {
+		// wire allows only one provider for a type, so the qualified bean can not be added along with another one
+		for _, b := range inj.Provided() {
+			if b.Identical(bean) {
+				return &QualifierConflictError{Injector: inj.String(), Bean: bean.Id(), Provided: b.Id()}
 			}
 		}
 	}
+	di.instantiate(bean)
+	list, _ := di.index.At(bean.Type()).([]*comm.Provider)
+	var screen screening
+	for _, p := range list {
+		if screen.pass(di, e, p) {
+			e.Candidates[p.String()] = p
+		}
+	}
+	if len(e.Candidates) == 0 && bean.Qualifier() == "" && bean.Kind() == comm.InterfaceKind {
+		// no provider give the interface directly, try binding an implementation
+		bind, p, err := di.bindFor(e)
+		if err != nil {
+			return err
+		}
+		e.Note = "no provider of the interface, the injector provides the only implementation"
+		if p != nil {
+			inj.AddProvider(p)
+			e.Note = "no provider of the interface, " + p.Label(relativeTo(inj.Package())) + " provides the only implementation"
+		}
+		inj.AddProvider(bind)
+		e.Winner = bind
+		return nil
+	}
+	if len(e.Candidates) == 0 {
+		if err := screen.err(e); err != nil {
+			return err
+		}
+	}
+	if len(e.Candidates) == 0 && bean.Qualifier() == "" {
+		// no provider function, try injecting fields of the struct
+		if p := di.structFor(inj, bean); p != nil {
+			inj.AddProvider(p)
+			e.Winner = p
+			e.Note = "no provider function, inject fields of the struct"
+			return nil
+		}
+	}
+	p, err := di.conf.ProviderElect(e)
+	if err != nil {
+		return err
+	}
+	e.Winner = p
+	inj.AddProvider(p)
+	return nil
+}
+
+// screening sort out the providers which can not be candidates of an election, by the reason
+type screening struct {
+	rejected     []*comm.Provider // declared in packages not allowed
+	failing      []*comm.Provider // errors or cleanups not fitting the injector
+	unresolvable []*comm.Provider // requiring primitives the injector does not give
+}
+
+// pass report whether p can be a candidate of e, otherwise the reason is recorded in e
+func (s *screening) pass(di *DIContext, e *Election, p *comm.Provider) bool {
+	inj, bean := e.Injector, e.Bean
+	if bean.Qualifier() != "" && p.Provide().Qualifier() != bean.Qualifier() {
+		return false
+	}
+	if !di.allowed(p.Package()) {
+		s.rejected = append(s.rejected, p)
+		e.Eliminate(p, "package not allowed")
+		return false
+	}
+	if ok, reason := inj.Accept(p); !ok {
+		s.failing = append(s.failing, p)
+		e.Eliminate(p, reason)
+		return false
+	}
+	if b := unresolvedPrimitive(inj, p); b != nil {
+		s.unresolvable = append(s.unresolvable, p)
+		e.Eliminate(p, "unresolvable primitive dependency "+beanLabel(b, relativeTo(inj.Package())))
+		return false
+	}
+	return true
+}
+
+// err report why the providers screened out can not be used, nil if there is none
+func (s *screening) err(e *Election) error {
+	switch {
+	case len(s.unresolvable) > 0:
+		return unresolvablePrimitive(e.Injector, e.Bean, s.unresolvable)
+	case len(s.failing) > 0:
+		return incompatibleProvider(e.Injector, e.Bean, s.failing)
+	case len(s.rejected) > 0:
+		return noAllowedProvider(e.Injector, e.Bean, s.rejected)
+	}
+	return nil
+}
+
+// allowed report whether providers declared in package path can be used, see ProcessConfigurer.PackagePredicate
+func (di *DIContext) allowed(path string) bool {
+	if ok, found := di.allowedPkgs[path]; found {
+		return ok
+	}
+	ok := true
+	if pkg := di.universe[path]; pkg != nil {
+		ok = di.conf.PackagePredicate(pkg)
+	}
+	di.allowedPkgs[path] = ok
+	return ok
+}
+
+func noAllowedProvider(inj *comm.Injector, bean *comm.Bean, rejected []*comm.Provider) error {
+	names := make([]string, 0, len(rejected))
+	for _, p := range rejected {
+		names = append(names, p.FullName())
+	}
+	slices.Sort(names)
+	return &NoAllowedProviderError{Injector: inj.String(), Bean: bean.Id(), Rejected: names}
+}
+
+// unresolvedPrimitive return the first primitive bean required by p but not provided by the injector
+func unresolvedPrimitive(inj *comm.Injector, p *comm.Provider) *comm.Bean {
+	provided := inj.Provided()
+	for _, b := range p.Require() {
+		if !b.Primitive() {
+			continue
+		}
+		if !slices.ContainsFunc(provided, b.Identical) {
+			return b
+		}
+	}
+	return nil
+}
+
+func unresolvablePrimitive(inj *comm.Injector, bean *comm.Bean, list []*comm.Provider) error {
+	names := make([]string, 0, len(list))
+	for _, p := range list {
+		names = append(names, p.FullName()+" ("+unresolvedPrimitive(inj, p).Id()+")")
+	}
+	slices.Sort(names)
+	return &UnresolvablePrimitiveError{Injector: inj.String(), Bean: bean.Id(), Candidates: names}
+}
+
+func incompatibleProvider(inj *comm.Injector, bean *comm.Bean, failing []*comm.Provider) error {
+	names := make([]string, 0, len(failing))
+	for _, p := range failing {
+		_, reason := inj.Accept(p)
+		names = append(names, p.FullName()+" ("+reason+")")
+	}
+	slices.Sort(names)
+	return &IncompatibleProviderError{Injector: inj.String(), Bean: bean.Id(), Candidates: names}
 }
 
 func (di *DIContext) refactor() {
 	refactored := map[string]bool{}
-	for _, file := range di.files {
-		file.Refactor()
+	for _, ref := range di.sortedFiles() {
+		file := di.files[ref]
+		file.SetSortArgs(di.conf.WillSortArgs())
+		if err := file.Refactor(); err != nil {
+			di.report(token.Position{}, err)
+			continue
+		}
 		refactored[file.Package()] = true
 	}
-	for path, pkg := range di.pkgs {
-		if di.conf.WillRewriteSource() && refactored[path] {
-			slog.Info("saving package", "package", path)
-			err := pkg.Save()
-			if err != nil {
-				panic(err)
-			}
+	paths := make([]string, 0, len(di.pkgs))
+	for path := range di.pkgs {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		if !refactored[path] {
+			continue
 		}
+		if !di.conf.WillRewriteSource() {
+			slog.Info("dry run, skip saving package", "package", path)
+			continue
+		}
+		slog.Info("saving package", "package", path)
+		err := savePackage(di.pkgs[path])
+		if err != nil {
+			di.report(token.Position{}, err)
+		}
+	}
+}
+
+// sortedInjectors return the injectors sorted by package path then name
+func (di *DIContext) sortedInjectors() []*comm.Injector {
+	injectors := make([]*comm.Injector, 0, len(di.injectors))
+	for _, inj := range di.injectors {
+		injectors = append(injectors, inj)
+	}
+	slices.SortFunc(injectors, func(a, b *comm.Injector) int {
+		return cmp.Or(cmp.Compare(a.Package(), b.Package()), cmp.Compare(a.Name(), b.Name()))
+	})
+	return injectors
+}
+
+// sortedFiles return the references of the wireinject files sorted by package path then file name
+func (di *DIContext) sortedFiles() []objRef {
+	refs := make([]objRef, 0, len(di.files))
+	for ref := range di.files {
+		refs = append(refs, ref)
 	}
+	slices.SortFunc(refs, func(a, b objRef) int {
+		return cmp.Or(cmp.Compare(a.importPath, b.importPath), cmp.Compare(a.name, b.name))
+	})
+	return refs
 }
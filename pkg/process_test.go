@@ -1,15 +1,20 @@
 package pkg
 
 import (
+	"errors"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/hauntedness/autowire/pkg/comm"
 )
 
 func TestDIContext_Process(t *testing.T) {
-	di := NewDIContext(nil)
+	di := NewDIContext(nil, nil)
 	path := "github.com/hauntedness/autowire/example"
-	di.Process(path)
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
 
 	assertNotEmpty[*comm.Provider](t, di.providers)
 	provider := di.providers[objRef{importPath: path, name: "NewEvent"}]
@@ -25,9 +30,11 @@ func TestDIContext_Process(t *testing.T) {
 
 // test complex dependencies, to see yanyan which is in very underlayer can be enriched
 func TestDIContext_Process2(t *testing.T) {
-	di := NewDIContext(nil)
+	di := NewDIContext(nil, nil)
 	path := "github.com/hauntedness/autowire/example/inj"
-	di.Process(path)
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
 
 	assertNotEmpty[*comm.Provider](t, di.providers)
 	provider := di.providers[objRef{importPath: path, name: "NewShu"}]
@@ -44,6 +51,41 @@ func TestDIContext_Process2(t *testing.T) {
 	assertNotNil(t, injector)
 }
 
+// test that a broken injector is reported with its position instead of panic
+func TestDIContext_ProcessError(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	err := di.Process("github.com/hauntedness/autowire/example/broken")
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != 1 {
+		t.Fatalf("expecting 1 error, got %v", err)
+	}
+	var notProvided *comm.OutputNotProvidedError
+	if !errors.As(list[0], &notProvided) {
+		t.Fatalf("expecting OutputNotProvidedError, got %v", list[0])
+	}
+	if filepath.Base(list[0].Pos.Filename) != "wire.go" || list[0].Pos.Line == 0 {
+		t.Fatalf("expecting position in wire.go, got %v", list[0].Pos)
+	}
+}
+
+func TestDIContext_Check(t *testing.T) {
+	// complete injector
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	if err := di.Check("github.com/hauntedness/autowire/example/inj"); err != nil {
+		t.Fatal(err)
+	}
+	// incomplete injector
+	di = NewDIContext(&DefaultProcessConfigurer{}, nil)
+	err := di.Check("github.com/hauntedness/autowire/example/incomplete")
+	var incomplete *IncompleteInjectorError
+	if !errors.As(err, &incomplete) {
+		t.Fatalf("expecting IncompleteInjectorError, got %v", err)
+	}
+	if len(incomplete.Providers) != 3 {
+		t.Fatalf("expecting 3 missing providers, got %v", incomplete.Providers)
+	}
+}
+
 func assertNotEmpty[E any, T ~[]E | ~map[objRef]E](t *testing.T, collect T) {
 	if len(collect) == 0 {
 		t.Fatalf("expecting not empty, got empty")
@@ -55,3 +97,55 @@ func assertNotNil[E any, PE *E](t *testing.T, obj PE) {
 		t.Fatalf("expecting not nil, got nil")
 	}
 }
+
+// test provider set in wire.Build is expanded, and providers its members need are added
+func TestDIContext_ProcessProviderSet(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	path := "github.com/hauntedness/autowire/example/set"
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
+	injector := di.injectors[objRef{importPath: path, name: "InitService"}]
+	assertNotNil(t, injector)
+	if !injector.Auto() {
+		t.Fatalf("expecting injector with provider set be autowired")
+	}
+	var added []string
+	for _, p := range injector.Added() {
+		added = append(added, p.Name())
+	}
+	if !slices.Equal(added, []string{"NewZhao", "NewConfig"}) {
+		t.Fatalf("expecting NewZhao and NewConfig added, got %v", added)
+	}
+}
+
+// test arguments of injector are taken as provided
+func TestDIContext_ProcessInjectorArgs(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	path := "github.com/hauntedness/autowire/example/args"
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
+	injector := di.injectors[objRef{importPath: path, name: "InitApp"}]
+	assertNotNil(t, injector)
+	var added []string
+	for _, p := range injector.Added() {
+		added = append(added, p.Name())
+	}
+	if !slices.Equal(added, []string{"NewZhao"}) {
+		t.Fatalf("expecting only NewZhao added, got %v", added)
+	}
+}
+
+// test providers in excluded packages are never used
+func TestDIContext_ProcessExcludedPackage(t *testing.T) {
+	di := NewDIContext(&DefaultProcessConfigurer{Exclude: []string{"*/elect/store"}}, nil)
+	err := di.Process("github.com/hauntedness/autowire/example/elect")
+	var notAllowed *NoAllowedProviderError
+	if !errors.As(err, &notAllowed) {
+		t.Fatalf("expecting NoAllowedProviderError, got %v", err)
+	}
+	if len(notAllowed.Rejected) != 2 {
+		t.Errorf("expecting NewCachedStore and NewStore rejected, got %v", notAllowed.Rejected)
+	}
+}
@@ -0,0 +1,35 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDIContext_Prune(t *testing.T) {
+	tests := []struct {
+		mode PruneMode
+		want []string
+	}{
+		{PruneNone, nil},
+		{PruneFuncs, []string{"+	wire.Build(NewShop, zhao.NewZhao, NoiseSet)", "-	\"github.com/hauntedness/autowire/example/prune/extra\""}},
+		{PruneAll, []string{"+	wire.Build(NewShop, zhao.NewZhao)\n"}},
+	}
+	for _, tt := range tests {
+		di := NewDIContext(&DefaultProcessConfigurer{Prune: tt.mode}, nil)
+		if err := di.Process("github.com/hauntedness/autowire/example/prune"); err != nil {
+			t.Fatal(err)
+		}
+		var sb strings.Builder
+		if err := di.Diff(&sb); err != nil {
+			t.Fatal(err)
+		}
+		if tt.mode == PruneNone && sb.Len() > 0 {
+			t.Errorf("expecting no change without pruning, got:\n%s", sb.String())
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(sb.String(), want) {
+				t.Errorf("mode %d: expecting diff contains %q, got:\n%s", tt.mode, want, sb.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package pkg
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+
+	"github.com/dave/dst"
+	"github.com/dave/dst/decorator"
+	"github.com/hauntedness/autowire/pkg/comm"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// providerSet is a provider set variable declared by wire.NewSet, nested sets are flattened
+type providerSet struct {
+	id        ProviderSetID
+	providers []*comm.Provider
+	// false if the set contains expressions autowire does not understand, e.g. wire.Value
+	complete bool
+}
+
+// requirePackage return the package of path with its providers loaded,
+// packages out of the universe are reported as LoadError, as their types would not be identical to the loaded ones
+func (di *DIContext) requirePackage(path string) (*decorator.Package, bool) {
+	if pkg := di.pkgs[path]; pkg != nil {
+		return pkg, true
+	}
+	p := di.universe[path]
+	if p == nil {
+		di.report(token.Position{}, &LoadError{Pattern: path, Msg: "package is not a dependency of the loaded packages"})
+		return nil, false
+	}
+	pkg, err := decorate(p)
+	if err != nil {
+		di.report(token.Position{}, &LoadError{Pattern: path, Msg: err.Error()})
+		return nil, false
+	}
+	di.pkgs[path] = pkg
+	di.loadProviderAndInjector(pkg, &LoadConfig{LoadMode: LoadProvider})
+	return pkg, true
+}
+
+// parseWireArgs interpret the arguments of wire.Build or wire.NewSet,
+// functions are taken as providers and provider sets are expanded into their providers,
+// complete is false if any argument can not be understood
+func (di *DIContext) parseWireArgs(pkg *decorator.Package, args []ast.Expr) (providers []*comm.Provider, complete bool) {
+	info := pkg.TypesInfo
+	complete = true
+	for _, arg := range args {
+		arg = astutil.Unparen(arg)
+		if call, ok := arg.(*ast.CallExpr); ok {
+			switch {
+			case isWireCall(info, call, "NewSet"):
+				list, ok := di.parseWireArgs(pkg, call.Args)
+				providers = append(providers, list...)
+				complete = complete && ok
+			case isWireCall(info, call, "Struct"):
+				st := parseStruct(pkg, call)
+				if st == nil {
+					complete = false
+					continue
+				}
+				providers = append(providers, st)
+			case isWireCall(info, call, "Value"), isWireCall(info, call, "InterfaceValue"):
+				value := parseValue(pkg, call)
+				if value == nil {
+					complete = false
+					continue
+				}
+				providers = append(providers, value)
+			case isWireCall(info, call, "Bind"):
+				bind := parseBind(pkg, call)
+				if bind == nil {
+					complete = false
+					continue
+				}
+				providers = append(providers, bind)
+			default:
+				complete = false
+			}
+			continue
+		}
+		if p := di.instanceArg(info, arg); p != nil {
+			providers = append(providers, p)
+			continue
+		}
+		switch obj := qualifiedIdentObject(info, arg).(type) {
+		case *types.Func:
+			providers = append(providers, di.funcProvider(obj))
+		case *types.Var:
+			if !isProviderSetType(obj.Type()) {
+				complete = false
+				continue
+			}
+			set := di.providerSet(obj)
+			providers = append(providers, set.providers...)
+			complete = complete && set.complete
+		default:
+			complete = false
+		}
+	}
+	return providers, complete
+}
+
+// parseBind parse wire.Bind(new(Iface), new(Impl)), return nil if call is malformed
+func parseBind(pkg *decorator.Package, call *ast.CallExpr) *comm.Provider {
+	if len(call.Args) != 2 {
+		return nil
+	}
+	iface, ok := pkg.TypesInfo.TypeOf(call.Args[0]).(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	impl, ok := pkg.TypesInfo.TypeOf(call.Args[1]).(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	return comm.NewBind(iface.Elem(), impl.Elem(), pkg.PkgPath, call.Pos())
+}
+
+// parseValue parse wire.Value(expr) or wire.InterfaceValue(new(Iface), expr), return nil if call is malformed
+func parseValue(pkg *decorator.Package, call *ast.CallExpr) *comm.Provider {
+	var iface types.Type
+	args := call.Args
+	if len(args) == 2 {
+		ptr, ok := pkg.TypesInfo.TypeOf(args[0]).(*types.Pointer)
+		if !ok {
+			return nil
+		}
+		iface, args = ptr.Elem(), args[1:]
+	}
+	if len(args) != 1 {
+		return nil
+	}
+	typ := pkg.TypesInfo.TypeOf(args[0])
+	expr, ok := pkg.Decorator.Dst.Nodes[args[0]].(dst.Expr)
+	if typ == nil || !ok {
+		return nil
+	}
+	return comm.NewValue(typ, iface, expr, pkg.PkgPath, call.Pos())
+}
+
+// parseStruct parse wire.Struct(new(T), "*") or wire.Struct(new(T), "Field1", "Field2"),
+// return nil if call is malformed
+func parseStruct(pkg *decorator.Package, call *ast.CallExpr) *comm.Provider {
+	if len(call.Args) < 2 {
+		return nil
+	}
+	ptr, ok := pkg.TypesInfo.TypeOf(call.Args[0]).(*types.Pointer)
+	if !ok {
+		return nil
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	if !ok {
+		return nil
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	if allFields(call) {
+		return comm.NewStruct(named, nil, false, pkg.PkgPath, call.Pos())
+	}
+	fields := make([]*types.Var, 0, len(call.Args)-1)
+	for _, arg := range call.Args[1:] {
+		f, err := checkField(arg, st)
+		if err != nil {
+			return nil
+		}
+		fields = append(fields, f)
+	}
+	return comm.NewStruct(named, fields, false, pkg.PkgPath, call.Pos())
+}
+
+// providerSet find the declaration of the provider set variable and expand it
+func (di *DIContext) providerSet(obj *types.Var) *providerSet {
+	id := ProviderSetID{ImportPath: obj.Pkg().Path(), VarName: obj.Name()}
+	if set := di.sets[id]; set != nil {
+		return set
+	}
+	set := &providerSet{id: id}
+	di.sets[id] = set
+	pkg, ok := di.requirePackage(id.ImportPath)
+	if !ok {
+		return set
+	}
+	// the package may be loaded separately, so look up the variable again
+	v, ok := pkg.Types.Scope().Lookup(id.VarName).(*types.Var)
+	if !ok {
+		return set
+	}
+	expr := varValue(pkg, v)
+	if expr == nil {
+		return set
+	}
+	set.providers, set.complete = di.parseWireArgs(pkg, []ast.Expr{expr})
+	return set
+}
+
+// varValue find the expression assigned to the package level variable obj
+func varValue(pkg *decorator.Package, obj *types.Var) ast.Expr {
+	pos := obj.Pos()
+	for _, f := range pkg.Package.Syntax {
+		tokenFile := pkg.Fset.File(f.Pos())
+		if base := tokenFile.Base(); base <= int(pos) && int(pos) < base+tokenFile.Size() {
+			path, _ := astutil.PathEnclosingInterval(f, pos, pos)
+			for _, node := range path {
+				spec, ok := node.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				for i, name := range spec.Names {
+					if name.Pos() == pos && i < len(spec.Values) {
+						return spec.Values[i]
+					}
+				}
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// isWireCall report whether call is a call to function name of wire package
+func isWireCall(info *types.Info, call *ast.CallExpr, name string) bool {
+	obj := qualifiedIdentObject(info, call.Fun)
+	return obj != nil && obj.Pkg() != nil && isWireImport(obj.Pkg().Path()) && obj.Name() == name
+}
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"go/token"
+	"go/types"
+
+	"github.com/hauntedness/autowire/pkg/comm"
+)
+
+// structFor create a wire.Struct provider for bean if it is a named struct or pointer to it,
+// return nil if the struct or its package is not allowed by the configurer or some of its fields can not be injected
+func (di *DIContext) structFor(inj *comm.Injector, bean *comm.Bean) *comm.Provider {
+	typ, ptr := bean.Type(), false
+	if p, ok := typ.(*types.Pointer); ok {
+		typ, ptr = p.Elem(), true
+	}
+	named, ok := typ.(*types.Named)
+	if !ok || named.Obj().Pkg() == nil {
+		return nil
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok || !di.allowed(named.Obj().Pkg().Path()) || !di.conf.StructPredicate(named.Obj()) {
+		return nil
+	}
+	var fieldTypes []types.Type
+	for i := 0; i < st.NumFields(); i++ {
+		if isPrevented(st.Tag(i)) {
+			continue
+		}
+		f := st.Field(i)
+		// unexported fields can only be set in the same package
+		if !f.Exported() && named.Obj().Pkg().Path() != inj.Package() {
+			return nil
+		}
+		if !satisfyBeanDefinition(f.Type()) {
+			return nil
+		}
+		// wire does not allow multiple fields of the same type
+		for _, t := range fieldTypes {
+			if types.Identical(t, f.Type()) {
+				return nil
+			}
+		}
+		fieldTypes = append(fieldTypes, f.Type())
+	}
+	return comm.NewStruct(named, nil, ptr, inj.Package(), token.NoPos)
+}
@@ -0,0 +1,29 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDIContext_StructProvider(t *testing.T) {
+	path := "github.com/hauntedness/autowire/example/structs"
+	// struct provider is opt-in
+	di := NewDIContext(&DefaultProcessConfigurer{}, nil)
+	var notFound *ProviderNotFoundError
+	if err := di.Process(path); !errors.As(err, &notFound) {
+		t.Fatalf("expecting ProviderNotFoundError, got %v", err)
+	}
+	di = NewDIContext(&DefaultProcessConfigurer{StructProvider: true}, nil)
+	if err := di.Process(path); err != nil {
+		t.Fatal(err)
+	}
+	var sb strings.Builder
+	if err := di.Diff(&sb); err != nil {
+		t.Fatal(err)
+	}
+	want := `wire.Struct(new(Server), "*")`
+	if !strings.Contains(sb.String(), want) {
+		t.Errorf("expecting diff contains %q, got:\n%s", want, sb.String())
+	}
+}
@@ -0,0 +1,119 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// context lines around each change of unified diff
+const diffContext = 3
+
+// noNewline is appended to the last line of text not ending with newline, so that the marker is printed after it
+const noNewline = "\n\\ No newline at end of file"
+
+// UnifiedDiff render the changes from old to new in unified format, return empty string if no change,
+// lines are split by LF and keep the CR of CRLF, a missing newline at end of text is marked as diff does
+func UnifiedDiff(oldName, newName string, old, new string) string {
+	if old == new {
+		return ""
+	}
+	a, b := splitLines(old), splitLines(new)
+	ops := diffLines(a, b)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", oldName, newName)
+	for start := 0; start < len(ops); {
+		// find the next change
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// extend the hunk until there are more than 2*diffContext unchanged lines
+		end := start
+		for i := start; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+		from, to := max(start-diffContext, 0), min(end+diffContext, len(ops))
+		hunk := ops[from:to]
+		oldStart, newStart := hunk[0].a+1, hunk[0].b+1
+		oldLen, newLen := 0, 0
+		for _, op := range hunk {
+			if op.kind != '+' {
+				oldLen++
+			}
+			if op.kind != '-' {
+				newLen++
+			}
+		}
+		if oldLen == 0 {
+			oldStart--
+		}
+		if newLen == 0 {
+			newStart--
+		}
+		fmt.Fprintf(&sb, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLen, newStart, newLen)
+		for _, op := range hunk {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.line)
+			sb.WriteByte('\n')
+		}
+		start = to
+	}
+	return sb.String()
+}
+
+// diffOp is a line of unified diff, a and b are the line index in old and new text
+type diffOp struct {
+	kind byte // one of ' ', '-', '+'
+	line string
+	a, b int
+}
+
+// diffLines compute line changes with longest common subsequence
+func diffLines(a, b []string) []diffOp {
+	// lcs[i][j] is the length of lcs of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	ops := make([]diffOp, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{kind: ' ', line: a[i], a: i, b: j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{kind: '-', line: a[i], a: i, b: j})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: '+', line: b[j], a: i, b: j})
+			j++
+		}
+	}
+	return ops
+}
+
+func splitLines(text string) []string {
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		return lines[:len(lines)-1]
+	}
+	lines[len(lines)-1] += noNewline
+	return lines
+}
@@ -1,10 +1,10 @@
 package util
 
 import (
-	"fmt"
 	"go/ast"
 )
 
+// Unwrap find the identifier of expr, return nil if expr is not an identifier, selector or star expression
 func Unwrap(expr ast.Expr) *ast.Ident {
 	switch v := expr.(type) {
 	case *ast.Ident:
@@ -14,6 +14,6 @@ func Unwrap(expr ast.Expr) *ast.Ident {
 	case *ast.StarExpr:
 		return Unwrap(v.X)
 	default:
-		panic(fmt.Errorf("error get ident from expr %v", expr))
+		return nil
 	}
 }